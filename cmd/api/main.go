@@ -3,13 +3,21 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/LeandroDeJesus-S/confectionery/internal/config/database"
+	"github.com/LeandroDeJesus-S/confectionery/internal/config/validation"
 	"github.com/LeandroDeJesus-S/confectionery/internal/controllers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/middlewares"
 	"github.com/LeandroDeJesus-S/confectionery/internal/routes"
-	"github.com/go-playground/validator/v10"
+	"github.com/LeandroDeJesus-S/confectionery/internal/search"
+	"github.com/LeandroDeJesus-S/confectionery/internal/storage"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+
+	// embeds the time zone database, so STORE_TIMEZONE is loaded on hosts
+	// without one
+	_ "time/tzdata"
 )
 
 func main() {
@@ -26,11 +34,38 @@ func main() {
 	log.Println("All migrations performed")
 
 	baseRouter := mux.NewRouter()
-	validator := validator.New(validator.WithRequiredStructEnabled())
+	idempotency := middlewares.NewIdempotency(db)
+	baseRouter.Use(middlewares.RequestID, middlewares.Language)
+	routes.SetupErrorHandlers(baseRouter)
+
+	validator, err := validation.NewValidator()
+	if err != nil {
+		log.Fatal("Cannot configure the validator: ", err)
+	}
+
+	log.Println("Store time zone:", controllers.StoreLocation())
+
+	imageStorage, err := storage.NewLocal(os.Getenv("IMAGES_DIR"))
+	if err != nil {
+		log.Fatal("Cannot set up the image storage: ", err)
+	}
+
+	routes.SetupCustomersRoutes(baseRouter, controllers.NewCustomerController(db, validator), idempotency)
+	routes.SetupCakeRoutes(baseRouter, controllers.NewCakeController(db, validator, imageStorage), idempotency)
+	routes.SetupOrdersRoutes(baseRouter, controllers.NewOrdersController(db, validator), idempotency)
+	routes.SetupDeliveryZoneRoutes(baseRouter, controllers.NewDeliveryZoneController(db, validator))
+
+	searchEngine, err := search.Setup(db)
+	if err != nil {
+		log.Fatal("Cannot set up the search index: ", err)
+	}
+	if !searchEngine.FullText() {
+		log.Println("SQLite FTS5 is not available, search falls back to LIKE queries")
+	}
+	routes.SetupSearchRoutes(baseRouter, controllers.NewSearchController(db, searchEngine))
+	routes.SetupSpreadsheetRoutes(baseRouter, controllers.NewSpreadsheetController(db, validator))
 
-	routes.SetupCustomersRoutes(baseRouter, controllers.NewCustomerController(db, validator))
-	routes.SetupCakeRoutes(baseRouter, controllers.NewCakeController(db, validator))
-	routes.SetupOrdersRoutes(baseRouter, controllers.NewOrdersController(db, validator))
+	routes.SetupDocsRoutes(baseRouter)
 	log.Println("All routes configured")
 
 	addr := ":8080"
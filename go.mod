@@ -3,20 +3,23 @@ module github.com/LeandroDeJesus-S/confectionery
 go 1.24.3
 
 require (
-	github.com/gabriel-vasile/mimetype v1.4.8 // indirect
-	github.com/go-playground/locales v0.14.1 // indirect
-	github.com/go-playground/universal-translator v0.18.1 // indirect
+	github.com/gabriel-vasile/mimetype v1.4.8
+	github.com/go-playground/locales v0.14.1
+	github.com/go-playground/universal-translator v0.18.1
 	github.com/go-playground/validator/v10 v10.26.0
 	github.com/gorilla/mux v1.8.1
+	github.com/joho/godotenv v1.5.1
+	golang.org/x/text v0.25.0
+	gorm.io/driver/sqlite v1.5.7
+	gorm.io/gorm v1.30.0
+)
+
+require (
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
-	github.com/joho/godotenv v1.5.1
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/mattn/go-sqlite3 v1.14.28 // indirect
 	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/net v0.34.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
-	golang.org/x/text v0.25.0 // indirect
-	gorm.io/driver/sqlite v1.5.7
-	gorm.io/gorm v1.30.0
 )
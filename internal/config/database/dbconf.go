@@ -3,10 +3,13 @@ package database
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/LeandroDeJesus-S/confectionery/internal/models"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // DatabaseStarter is an structure to manage the database startup processes
@@ -14,25 +17,95 @@ type DatabaseStarter struct {
 	db *gorm.DB
 }
 
-// NewDatabaseStarter initializes the DatabaseStarter struct
+// NewDatabaseStarter initializes the DatabaseStarter struct. Foreign keys are
+// enforced on every SQLite connection, and the driver errors are translated
+// to gorm ones such as gorm.ErrForeignKeyViolated.
 func NewDatabaseStarter() *DatabaseStarter {
-	db, err := gorm.Open(sqlite.Open(os.Getenv("DB_STRING")), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(withForeignKeys(os.Getenv("DB_STRING"))), &gorm.Config{
+		TranslateError: true,
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
 	return &DatabaseStarter{db: db}
 }
 
+// withForeignKeys adds the parameter enabling the SQLite foreign key
+// constraints to the connection string. Since the pragma is per connection,
+// setting it on the DSN applies it to every connection of the pool.
+func withForeignKeys(dsn string) string {
+	if strings.Contains(dsn, "_foreign_keys=") || strings.Contains(dsn, "_fk=") {
+		return dsn
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_foreign_keys=on"
+	}
+	return dsn + "?_foreign_keys=on"
+}
+
 // DB returns the pointer to the GORM DB instance.
 func (d *DatabaseStarter) DB() *gorm.DB {
 	return d.db
 }
 
+// dataMigrations are the statements backfilling the rows stored before a
+// change of the models, run in order after the tables are migrated. Each one
+// is applied once on each database, recorded under its version, since the
+// rows it matches may later be stored again on purpose.
+var dataMigrations = []struct {
+	version   string
+	statement string
+}{
+	// orders placed before their totals were stored are priced by their cake
+	{
+		version: "001_order_totals",
+		statement: "UPDATE orders SET total = qtd * (SELECT price FROM cakes WHERE cakes.id = orders.cake_id) " +
+			"WHERE total = 0 AND delivery_fee = 0",
+	},
+	// orders placed before variants keep the unit price they were charged
+	{
+		version: "002_order_unit_prices",
+		statement: "UPDATE orders SET unit_price = CASE WHEN qtd > 0 THEN (total - delivery_fee) / qtd " +
+			"ELSE (SELECT price FROM cakes WHERE cakes.id = orders.cake_id) END " +
+			"WHERE unit_price = 0 AND sku = ''",
+	},
+}
+
 // MakeMigrations performs all the migrations process
 func (d *DatabaseStarter) MakeMigrations() {
-	d.db.AutoMigrate(
+	err := d.db.AutoMigrate(
 		&models.Customer{},
+		&models.Address{},
+		&models.DeliveryZone{},
 		&models.Cake{},
+		&models.CakeOption{},
+		&models.CakeImage{},
 		&models.Order{},
+		&models.IdempotencyKey{},
+		&models.SchemaMigration{},
 	)
+	if err != nil {
+		log.Fatal("Cannot perform the migrations: ", err)
+	}
+
+	for _, migration := range dataMigrations {
+		if err := d.migrateData(migration.version, migration.statement); err != nil {
+			log.Fatal("Cannot perform the migrations: ", err)
+		}
+	}
+}
+
+// migrateData runs the statement of a data migration, unless its version was
+// already applied, and records it in the same transaction. The version is
+// recorded first, so servers starting together race for its primary key and
+// only one of them runs the statement.
+func (d *DatabaseStarter) migrateData(version, statement string) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		migration := models.SchemaMigration{Version: version, AppliedAt: time.Now()}
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&migration)
+		if created.Error != nil || created.RowsAffected == 0 {
+			return created.Error
+		}
+		return tx.Exec(statement).Error
+	})
 }
@@ -0,0 +1,232 @@
+package validation
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/i18n"
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/jsonschema"
+	"github.com/go-playground/validator/v10"
+)
+
+// NewValidator initializes the validator shared by the controllers. Field
+// errors are reported with the JSON name of the field instead of the Go one,
+// and can be translated to every language supported by the i18n package.
+func NewValidator() (*validator.Validate, error) {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	// the rules of the patch fields apply to the value being written
+	v.RegisterCustomTypeFunc(
+		patchFieldValue,
+		schemas.Optional[string]{},
+		schemas.Optional[uint]{},
+		schemas.Optional[uint64]{},
+		schemas.Optional[int64]{},
+		schemas.Optional[[]uint]{},
+		schemas.Optional[bool]{},
+		schemas.Optional[[]string]{},
+		schemas.Optional[[]schemas.AllergySchema]{},
+		schemas.Optional[*schemas.NutritionSchema]{},
+		schemas.Optional[json.RawMessage]{},
+	)
+
+	if err := v.RegisterValidation("cep", validateCEP); err != nil {
+		return nil, err
+	}
+	if err := v.RegisterValidation("phone", validatePhone); err != nil {
+		return nil, err
+	}
+	if err := v.RegisterValidation("cpf", validateCPF); err != nil {
+		return nil, err
+	}
+	if err := v.RegisterValidation("tag", validateTag); err != nil {
+		return nil, err
+	}
+	if err := v.RegisterValidation("jsonschema", validateJSONSchema); err != nil {
+		return nil, err
+	}
+	v.RegisterAlias("allergen", "oneof="+strings.Join(models.Allergens, " "))
+	v.RegisterAlias("diet", "oneof="+strings.Join(models.Diets, " "))
+	v.RegisterAlias("weekday", "oneof="+strings.Join(models.Weekdays, " "))
+	v.RegisterAlias("date", "datetime="+time.DateOnly)
+	v.RegisterStructValidation(validateCustomerContact, schemas.CustomerInputSchema{})
+
+	if err := i18n.RegisterValidatorTranslations(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func patchFieldValue(field reflect.Value) any {
+	return field.Interface().(schemas.PatchField).ValidationValue()
+}
+
+// validateJSONSchema implements the "jsonschema" tag, accepting the JSON
+// Schemas of objects parsed by jsonschema.Parse, or null.
+func validateJSONSchema(fl validator.FieldLevel) bool {
+	data := fl.Field().Bytes()
+	if string(data) == "null" {
+		return true
+	}
+	schema, err := jsonschema.Parse(data)
+	return err == nil && schema.Type == "object"
+}
+
+// cepPattern matches the Brazilian postal codes, with or without the hyphen
+var cepPattern = regexp.MustCompile(`^\d{5}-?\d{3}$`)
+
+// validateCEP implements the "cep" tag, accepting CEPs such as 01310-100 and
+// 01310100.
+func validateCEP(fl validator.FieldLevel) bool {
+	return cepPattern.MatchString(fl.Field().String())
+}
+
+// CEPDigits returns the digits of a CEP accepted by the "cep" tag, the form
+// they are stored in.
+func CEPDigits(cep string) string {
+	return strings.ReplaceAll(strings.TrimSpace(cep), "-", "")
+}
+
+// validatePhone implements the "phone" tag, accepting the numbers NormalizePhone
+// can write in the E.164 format.
+func validatePhone(fl validator.FieldLevel) bool {
+	_, ok := NormalizePhone(fl.Field().String())
+	return ok
+}
+
+// NormalizePhone returns the phone in the E.164 format, such as +5511912345678,
+// and whether it is valid. Spaces, dots, hyphens and parentheses are ignored.
+// Numbers without the leading + are taken as Brazilian ones with the area code,
+// optionally preceded by the 0 trunk prefix or by the 55 country code, and the
+// Brazilian numbers must have a valid area code and, for mobile phones, the
+// leading 9.
+func NormalizePhone(phone string) (string, bool) {
+	phone = strings.TrimSpace(phone)
+	international := strings.HasPrefix(phone, "+")
+	if international {
+		phone = phone[1:]
+	}
+
+	var digits strings.Builder
+	for _, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case unicode.IsSpace(r) || strings.ContainsRune(".-()", r):
+		default:
+			return "", false
+		}
+	}
+	number := digits.String()
+
+	if !international {
+		number = strings.TrimPrefix(number, "0")
+		if len(number) == 10 || len(number) == 11 {
+			number = "55" + number
+		}
+	}
+
+	switch {
+	case len(number) < 8 || len(number) > 15 || number[0] == '0':
+		return "", false
+	case !international && !strings.HasPrefix(number, "55"):
+		return "", false
+	case strings.HasPrefix(number, "55") && !brazilianPhone(number[2:]):
+		return "", false
+	}
+	return "+" + number, true
+}
+
+// brazilianPhone reports whether the national number, the area code followed
+// by the 8 digits of a landline or the 9 digits of a mobile phone, is valid.
+func brazilianPhone(number string) bool {
+	switch {
+	case len(number) != 10 && len(number) != 11:
+		return false
+	case number[0] == '0' || number[1] == '0':
+		return false
+	case len(number) == 11:
+		return number[2] == '9'
+	default:
+		// landlines start with 2 to 5
+		return number[2] >= '2' && number[2] <= '5'
+	}
+}
+
+// cpfPattern matches the CPFs, formatted as 123.456.789-09 or only with digits
+var cpfPattern = regexp.MustCompile(`^(\d{3}\.\d{3}\.\d{3}-\d{2}|\d{11})$`)
+
+// validateCPF implements the "cpf" tag, checking the format and the check
+// digits of the CPF.
+func validateCPF(fl validator.FieldLevel) bool {
+	cpf := strings.TrimSpace(fl.Field().String())
+	if !cpfPattern.MatchString(cpf) {
+		return false
+	}
+
+	digits := CPFDigits(cpf)
+	// the CPFs made of a single repeated digit pass the check but are not issued
+	if strings.Count(digits, digits[:1]) == len(digits) {
+		return false
+	}
+	return cpfCheckDigit(digits[:9]) == digits[9] && cpfCheckDigit(digits[:10]) == digits[10]
+}
+
+// cpfCheckDigit computes the check digit following the given digits, with
+// weights decreasing down to 2 from the last one.
+func cpfCheckDigit(digits string) byte {
+	sum := 0
+	for i, d := range digits {
+		sum += int(d-'0') * (len(digits) + 1 - i)
+	}
+	rest := sum % 11
+	if rest < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - rest)
+}
+
+// CPFDigits returns the 11 digits of a CPF accepted by the "cpf" tag, the
+// form they are stored in.
+func CPFDigits(cpf string) string {
+	return strings.NewReplacer(".", "", "-", "").Replace(strings.TrimSpace(cpf))
+}
+
+// validateCustomerContact requires the customers to have at least one way to
+// be contacted, an email or a phone, reporting the "contact" rule on both
+// fields otherwise.
+func validateCustomerContact(sl validator.StructLevel) {
+	customer := sl.Current().Interface().(schemas.CustomerInputSchema)
+	if strings.TrimSpace(customer.Email) != "" || strings.TrimSpace(customer.Phone) != "" {
+		return
+	}
+	sl.ReportError(customer.Email, "email", "Email", "contact", "")
+	sl.ReportError(customer.Phone, "phone", "Phone", "contact", "")
+}
+
+// tagPattern matches the tags of the customers and cakes: up to 30 letters, digits,
+// spaces and hyphens, starting with a letter or a digit
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N} -]{0,29}$`)
+
+// validateTag implements the "tag" tag, for the tags of the customers and
+// cakes and the categories of the cakes.
+func validateTag(fl validator.FieldLevel) bool {
+	return tagPattern.MatchString(strings.TrimSpace(fl.Field().String()))
+}
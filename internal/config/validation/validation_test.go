@@ -0,0 +1,83 @@
+package validation
+
+import "testing"
+
+func TestCPF(t *testing.T) {
+	v, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator() error = %v", err)
+	}
+
+	tests := []struct {
+		cpf   string
+		valid bool
+	}{
+		{"529.982.247-25", true},
+		{"52998224725", true},
+		{" 529.982.247-25 ", true},
+		{"111.444.777-35", true},
+		{"529.982.247-26", false},
+		{"529.982.247-15", false},
+		{"111.111.111-11", false},
+		{"00000000000", false},
+		{"529982247-25", false},
+		{"529.982.24725", false},
+		{"5299822472", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cpf, func(t *testing.T) {
+			err := v.Var(tt.cpf, "cpf")
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("cpf %q valid = %v, want %v", tt.cpf, valid, tt.valid)
+			}
+		})
+	}
+}
+
+func TestCPFDigits(t *testing.T) {
+	for _, cpf := range []string{"529.982.247-25", "52998224725", " 529.982.247-25 "} {
+		if got := CPFDigits(cpf); got != "52998224725" {
+			t.Errorf("CPFDigits(%q) = %q, want %q", cpf, got, "52998224725")
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone string
+		want  string
+		ok    bool
+	}{
+		{"(11) 91234-5678", "+5511912345678", true},
+		{"11912345678", "+5511912345678", true},
+		{"011 3456-7890", "+551134567890", true},
+		{"55 11 91234-5678", "+5511912345678", true},
+		{"+55 (11) 3456.7890", "+551134567890", true},
+		{"+1 415 555 2671", "+14155552671", true},
+		{"+44 20 7946 0958", "+442079460958", true},
+		{"(11) 1234-567", "", false},
+		{"12345678", "", false},
+		{"1 415 555 2671", "", false},
+		{"+55 11 1234-5678", "", false},
+		{"+55 11 81234-5678", "", false},
+		{"(01) 91234-5678", "", false},
+		{"(10) 91234-5678", "", false},
+		{"(11) 6234-5678", "", false},
+		{"+0 415 555 2671", "", false},
+		{"+1 234", "", false},
+		{"+1234567890123456", "", false},
+		{"11 91234-5678 ramal 2", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phone, func(t *testing.T) {
+			got, ok := NormalizePhone(tt.phone)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("NormalizePhone(%q) = %q, %v, want %q, %v", tt.phone, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
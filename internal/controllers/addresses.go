@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/config/validation"
+	"github.com/LeandroDeJesus-S/confectionery/internal/mappers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/patch"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// GetCustomerAddresses retrieves the addresses of a customer by ID, the
+// default one first, and encodes them as a JSON response with a 200 OK status
+// code.
+//
+// If the ID is invalid, the function will return a 400 Bad Request response.
+//
+// If the customer is not found, the function will return a 404 Not Found response.
+func (c *CustomerController) GetCustomerAddresses(w http.ResponseWriter, r *http.Request) {
+	dbCustomer, ok := c.customer(w, r)
+	if !ok {
+		return
+	}
+
+	var dbAddresses []models.Address
+	err := c.db.Where("customer_id = ?", dbCustomer.ID).Order("is_default DESC, id").Find(&dbAddresses).Error
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Something went wrong") {
+		return
+	}
+	respond(w, r, http.StatusOK, mappers.Addresses(dbAddresses))
+}
+
+// CreateCustomerAddress adds an address to the customer of the given ID and
+// returns it as a JSON response with a 201 Created status code. The first
+// address of the customer, or one created with "default": true, becomes its
+// default address.
+//
+// If the ID or the request body is invalid, the function will return a 400 Bad Request
+// response, and if the customer is not found, a 404 Not Found response.
+func (c *CustomerController) CreateCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	dbCustomer, ok := c.customer(w, r)
+	if !ok {
+		return
+	}
+
+	var inputAddress schemas.AddressInputSchema
+	err := json.NewDecoder(r.Body).Decode(&inputAddress)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid input") {
+		return
+	}
+
+	if !errorhandling.CheckValidationError(c.validator.Struct(inputAddress), w) {
+		return
+	}
+
+	dbAddress := models.Address{
+		CustomerID:   dbCustomer.ID,
+		Label:        inputAddress.Label,
+		Street:       inputAddress.Street,
+		Number:       inputAddress.Number,
+		Complement:   inputAddress.Complement,
+		Neighborhood: inputAddress.Neighborhood,
+		City:         inputAddress.City,
+		CEP:          validation.CEPDigits(inputAddress.CEP),
+		Default:      inputAddress.Default,
+	}
+
+	err = c.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Address{}).Where("customer_id = ?", dbCustomer.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			dbAddress.Default = true
+		}
+		if err := tx.Create(&dbAddress).Error; err != nil {
+			return err
+		}
+		if dbAddress.Default {
+			return unsetDefaultAddresses(tx, dbAddress)
+		}
+		return nil
+	})
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Something went wrong") {
+		return
+	}
+
+	w.Header().Set("ETag", httphelpers.ETag(dbAddress.Version))
+	respond(w, r, http.StatusCreated, mappers.Address(dbAddress))
+}
+
+// UpdateCustomerAddress updates an address of a customer with a JSON Merge
+// Patch or a JSON Patch and returns it as a JSON response with a 200 OK
+// status code. Setting "default" to true makes it the default address of the
+// customer.
+//
+// If the IDs or the request body are invalid, the function will return a 400 Bad Request
+// response, and if the address is not found, a 404 Not Found response.
+//
+// If the If-Match header does not match the ETag of the address, or the address is changed
+// by another request meanwhile, the function will return a 412 Precondition Failed response.
+func (c *CustomerController) UpdateCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	dbAddress, ok := c.address(w, r)
+	if !ok {
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbAddress.Version)) {
+		return
+	}
+
+	var inputAddress schemas.AddressPatchInputSchema
+	if !errorhandling.CheckPatchError(patch.Decode(r, dbAddress, &inputAddress), w) {
+		return
+	}
+
+	if !errorhandling.CheckValidationError(c.validator.Struct(inputAddress), w) {
+		return
+	}
+
+	updates := patch.Updates(inputAddress)
+	if inputAddress.CEP.Set {
+		updates["CEP"] = validation.CEPDigits(inputAddress.CEP.Value)
+	}
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		updated, err := updateVersioned(tx, &dbAddress, dbAddress.Version, updates)
+		switch {
+		case err != nil:
+			return err
+		case !updated:
+			return errVersionConflict
+		case inputAddress.Default.Set:
+			return unsetDefaultAddresses(tx, dbAddress)
+		}
+		return nil
+	})
+
+	switch err {
+	case nil:
+		w.Header().Set("ETag", httphelpers.ETag(dbAddress.Version))
+		respond(w, r, http.StatusOK, mappers.Address(dbAddress))
+
+	case errVersionConflict:
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Something went wrong")
+	}
+}
+
+// DeleteCustomerAddress removes an address of a customer and returns a 204 No
+// Content response. The orders delivered to it keep referencing it, and when
+// it was the default address the oldest remaining one takes its place.
+//
+// If the IDs are invalid, the function will return a 400 Bad Request response, and if
+// the address is not found, a 404 Not Found response.
+//
+// If the If-Match header does not match the ETag of the address, the function will return
+// a 412 Precondition Failed response.
+func (c *CustomerController) DeleteCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	dbAddress, ok := c.address(w, r)
+	if !ok {
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbAddress.Version)) {
+		return
+	}
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		deleted := tx.Where("version = ?", dbAddress.Version).Delete(&dbAddress)
+		switch {
+		case deleted.Error != nil:
+			return deleted.Error
+		case deleted.RowsAffected == 0:
+			return errVersionConflict
+		case !dbAddress.Default:
+			return nil
+		}
+
+		var next models.Address
+		switch err := tx.Where("customer_id = ?", dbAddress.CustomerID).Order("id").First(&next).Error; err {
+		case nil:
+			_, err := updateVersioned(tx, &next, next.Version, map[string]any{"Default": true})
+			return err
+		case gorm.ErrRecordNotFound:
+			return nil
+		default:
+			return err
+		}
+	})
+
+	switch err {
+	case nil:
+		respond(w, r, http.StatusNoContent, nil)
+
+	case errVersionConflict:
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Something went wrong")
+	}
+}
+
+// address retrieves the address of the IDs on the URL, which must belong to
+// the customer. If the IDs are invalid or the address does not exist, a
+// problem response is written and false is returned.
+func (c *CustomerController) address(w http.ResponseWriter, r *http.Request) (models.Address, bool) {
+	var dbAddress models.Address
+
+	vars := mux.Vars(r)
+	customerID, err := strconv.Atoi(vars["id"])
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid customer ID") {
+		return dbAddress, false
+	}
+	addressID, err := strconv.Atoi(vars["addressId"])
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid address ID") {
+		return dbAddress, false
+	}
+
+	result := c.db.First(&dbAddress, "id = ? AND customer_id = ?", addressID, customerID)
+	switch result.Error {
+	case nil:
+		return dbAddress, true
+
+	case gorm.ErrRecordNotFound:
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Address not found")
+		return dbAddress, false
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return dbAddress, false
+	}
+}
+
+// unsetDefaultAddresses leaves the given address as the only default address
+// of its customer.
+func unsetDefaultAddresses(tx *gorm.DB, dbAddress models.Address) error {
+	return tx.Model(&models.Address{}).
+		Where("customer_id = ? AND id <> ? AND is_default = ?", dbAddress.CustomerID, dbAddress.ID, true).
+		Updates(map[string]any{"Default": false, "Version": gorm.Expr("version + 1")}).Error
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"gorm.io/gorm"
+)
+
+// maxBulkItems is the number of items accepted by a single bulk request
+const maxBulkItems = 100
+
+// Modes of the bulk endpoints, chosen with ?mode=
+const (
+	// bulkAtomic applies every item or none of them
+	bulkAtomic = "atomic"
+	// bulkPartial applies the valid items and reports the rejected ones
+	bulkPartial = "partial"
+)
+
+// errBulkItemFailed rolls back the savepoint of a rejected item, or the whole
+// transaction of an atomic bulk request
+var errBulkItemFailed = errors.New("bulk item failed")
+
+// errBulkDryRun rolls back the transaction of a dry run
+var errBulkDryRun = errors.New("bulk dry run")
+
+// bulkOptions are the query parameters of the bulk requests
+type bulkOptions struct {
+	mode   string
+	dryRun bool
+}
+
+// parseBulkOptions parses the ?mode= and ?dryRun= parameters of a bulk
+// request. If they are invalid, a 400 Bad Request problem is written and
+// false is returned.
+func parseBulkOptions(w http.ResponseWriter, r *http.Request) (bulkOptions, bool) {
+	options := bulkOptions{mode: r.URL.Query().Get("mode")}
+	switch options.mode {
+	case "":
+		options.mode = bulkAtomic
+	case bulkAtomic, bulkPartial:
+		break
+	default:
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid mode parameter")
+		return options, false
+	}
+
+	var err error
+	options.dryRun, err = httphelpers.QueryBool(r, "dryRun")
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid dryRun parameter") {
+		return options, false
+	}
+	return options, true
+}
+
+// runBulk processes the items of a bulk request as described on executeBulk,
+// with the options of the query string.
+func runBulk(
+	w http.ResponseWriter, r *http.Request, db *gorm.DB, count int, status int,
+	process func(tx *gorm.DB, index int) schemas.BulkItemResult,
+) {
+	options, ok := parseBulkOptions(w, r)
+	if !ok {
+		return
+	}
+
+	if count == 0 || count > maxBulkItems {
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Bulk requests take from 1 to 100 items")
+		return
+	}
+
+	executeBulk(w, r, db, options, count, status, process)
+}
+
+// executeBulk processes the items of a bulk request in a single transaction,
+// each one in a savepoint, so a rejected item leaves no partial changes. On
+// the default atomic mode the whole transaction is rolled back when any item
+// is rejected, and a 422 Unprocessable Entity response reports the rejected
+// items, the others getting a 424 Failed Dependency status. With
+// ?mode=partial the accepted items are committed and a 207 Multi-Status
+// response is returned when some of them are rejected. When every item is
+// accepted the response has the given status. Dry runs are always rolled
+// back and report the status of every item with a 200 OK response.
+//
+// process handles the item of the given index, returning its result with an
+// error problem when it is rejected.
+func executeBulk(
+	w http.ResponseWriter, r *http.Request, db *gorm.DB, options bulkOptions, count int, status int,
+	process func(tx *gorm.DB, index int) schemas.BulkItemResult,
+) {
+	output := schemas.BulkOutputSchema{Results: make([]schemas.BulkItemResult, count)}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i := range count {
+			var result schemas.BulkItemResult
+			err := tx.Transaction(func(itemTx *gorm.DB) error {
+				result = process(itemTx, i)
+				if result.Error != nil {
+					return errBulkItemFailed
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errBulkItemFailed) {
+				return err
+			}
+
+			result.Index = i
+			output.Results[i] = result
+			if result.Error != nil {
+				output.Failed++
+			} else {
+				output.Succeeded++
+			}
+		}
+
+		switch {
+		case options.dryRun:
+			return errBulkDryRun
+		case options.mode == bulkAtomic && output.Failed > 0:
+			return errBulkItemFailed
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errBulkDryRun):
+		// the ids of the records created would not exist
+		for i := range output.Results {
+			output.Results[i].ID, output.Results[i].Data = 0, nil
+		}
+		respond(w, r, http.StatusOK, output)
+
+	case err == nil && output.Failed == 0:
+		respond(w, r, status, output)
+
+	case err == nil:
+		respond(w, r, http.StatusMultiStatus, output)
+
+	case errors.Is(err, errBulkItemFailed):
+		for i, result := range output.Results {
+			if result.Error == nil {
+				output.Results[i] = schemas.BulkItemResult{
+					Index:  result.Index,
+					Row:    result.Row,
+					Status: http.StatusFailedDependency,
+					Error: errorhandling.NewProblem(
+						w, http.StatusFailedDependency, "Rolled back because another item failed",
+					),
+				}
+			}
+		}
+		output.Succeeded, output.Failed = 0, count
+		respond(w, r, http.StatusUnprocessableEntity, output)
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// rejectedItem returns the result of a bulk item rejected with the given problem.
+func rejectedItem(problem *schemas.Problem) schemas.BulkItemResult {
+	return schemas.BulkItemResult{Status: problem.Status, Error: problem}
+}
@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/mappers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/storage"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/imaging"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxImageUploadSize is the largest body accepted by the image uploads
+	maxImageUploadSize = 20 << 20
+	// maxImagesPerUpload is the number of images sent at once
+	maxImagesPerUpload = 10
+	// maxImagePixels guards the decoding of images with huge dimensions,
+	// which take much more memory than their compressed size suggests: up to
+	// 8 bytes a pixel for 16-bit PNGs, so about 200 MB
+	maxImagePixels = 24_000_000
+	// thumbnailSize is the largest side of the thumbnails, in pixels
+	thumbnailSize = 320
+	// imageCacheControl lets the clients cache the images for a year, since
+	// the files served for an image never change
+	imageCacheControl = "public, max-age=31536000, immutable"
+)
+
+// ImageTypes lists the media types of the images accepted for the cakes
+var ImageTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// imageDecoding holds the slot of the single image decoded at a time by the
+// server, so the memory taken by the uploads stays within the one of the
+// largest image accepted, however many are sent at once
+var imageDecoding = make(chan struct{}, 1)
+
+var (
+	errUnsupportedImage = errors.New("unsupported image type")
+	errInvalidImage     = errors.New("invalid image")
+	errImageTooLarge    = errors.New("image too large")
+)
+
+// imageUpload is an uploaded image checked and resized, ready to be stored.
+type imageUpload struct {
+	content            []byte
+	contentType        string
+	extension          string
+	width, height      int
+	thumbnail          bytes.Buffer
+	thumbnailType      string
+	thumbnailExtension string
+}
+
+// GetCakeImages retrieves the images of a cake by ID and encodes them as a
+// JSON response with a 200 OK status code.
+//
+// If the ID is invalid, the function will return a 400 Bad Request response, and if the
+// cake is not found, a 404 Not Found response.
+func (c *CakeController) GetCakeImages(w http.ResponseWriter, r *http.Request) {
+	dbCake, ok := c.cake(w, r)
+	if !ok {
+		return
+	}
+
+	var dbImages []models.CakeImage
+	err := c.db.Where("cake_id = ?", dbCake.ID).Order("id").Find(&dbImages).Error
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+	respond(w, r, http.StatusOK, mappers.CakeImages(dbImages))
+}
+
+// UploadCakeImages adds the images sent on the "images" fields of a
+// multipart/form-data body to the cake of the given ID, and returns them as
+// a JSON response with a 201 Created status code. The type of each image is
+// detected from its content, ignoring the declared one, and a thumbnail is
+// generated for it. Either every image is added or none is.
+//
+// If the ID is invalid, no image or too many images are sent, or an image cannot be
+// decoded or is too large, the function will return a 400 Bad Request response, and if
+// the cake is not found, a 404 Not Found response. A body over maxImageUploadSize gets a
+// 413 Content Too Large response, and a body other than multipart/form-data or an image
+// other than JPEG, PNG or GIF a 415 Unsupported Media Type response.
+func (c *CakeController) UploadCakeImages(w http.ResponseWriter, r *http.Request) {
+	dbCake, ok := c.cake(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUploadSize)
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		errorhandling.ProblemResponse(w, http.StatusUnsupportedMediaType, "Images must be sent as multipart/form-data")
+		return
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	err = r.ParseMultipartForm(maxImageUploadSize)
+	switch {
+	case errors.As(err, &maxBytesErr):
+		errorhandling.ProblemResponse(w, http.StatusRequestEntityTooLarge, "The images exceed 20 MB")
+		return
+	case err != nil:
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid input")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	headers := r.MultipartForm.File["images"]
+	switch {
+	case len(headers) == 0:
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "No images were sent")
+		return
+	case len(headers) > maxImagesPerUpload:
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Up to 10 images are sent at once")
+		return
+	}
+
+	uploads := make([]*imageUpload, 0, len(headers))
+	for _, header := range headers {
+		upload, err := readImage(r.Context(), header)
+		switch {
+		case err == nil:
+			uploads = append(uploads, upload)
+		case r.Context().Err() != nil:
+			// the client is gone
+			return
+		case errors.Is(err, errUnsupportedImage):
+			errorhandling.ProblemResponse(w, http.StatusUnsupportedMediaType, "Only JPEG, PNG and GIF images are accepted")
+			return
+		case errors.Is(err, errImageTooLarge):
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Images must have up to 24 megapixels")
+			return
+		case errors.Is(err, errInvalidImage):
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid image")
+			return
+		default:
+			errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+	}
+
+	dbImages := make([]models.CakeImage, 0, len(uploads))
+	for _, upload := range uploads {
+		dbImage, err := c.storeImage(dbCake.ID, upload)
+		if err != nil {
+			c.deleteImageFiles(dbImages)
+			errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		dbImages = append(dbImages, dbImage)
+	}
+
+	err = c.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&dbImages).Error; err != nil {
+			return err
+		}
+		return touchCake(tx, dbCake.ID)
+	})
+	switch {
+	case err == nil:
+		respond(w, r, http.StatusCreated, mappers.CakeImages(dbImages))
+
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		// the cake was deleted meanwhile
+		c.deleteImageFiles(dbImages)
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Cake not found")
+
+	default:
+		c.deleteImageFiles(dbImages)
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// GetCakeImage serves an image of a cake as uploaded, with caching headers
+// letting the clients keep it for a year. The ETag and Last-Modified headers
+// are set, so conditional requests get a 304 Not Modified response, and
+// range requests are supported.
+//
+// If the IDs are invalid, the function will return a 400 Bad Request response, and if
+// the image is not found, a 404 Not Found response.
+func (c *CakeController) GetCakeImage(w http.ResponseWriter, r *http.Request) {
+	dbImage, ok := c.cakeImage(w, r)
+	if !ok {
+		return
+	}
+	c.serveImage(w, r, dbImage.Key, dbImage.ContentType, dbImage.CreatedAt)
+}
+
+// GetCakeImageThumbnail serves the thumbnail of an image of a cake, fitting
+// a square of thumbnailSize pixels, like GetCakeImage.
+func (c *CakeController) GetCakeImageThumbnail(w http.ResponseWriter, r *http.Request) {
+	dbImage, ok := c.cakeImage(w, r)
+	if !ok {
+		return
+	}
+	c.serveImage(w, r, dbImage.ThumbnailKey, dbImage.ThumbnailType, dbImage.CreatedAt)
+}
+
+// DeleteCakeImage removes an image of a cake and its thumbnail and returns a
+// 204 No Content response.
+//
+// If the IDs are invalid, the function will return a 400 Bad Request response, and if
+// the image is not found, a 404 Not Found response.
+func (c *CakeController) DeleteCakeImage(w http.ResponseWriter, r *http.Request) {
+	dbImage, ok := c.cakeImage(w, r)
+	if !ok {
+		return
+	}
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&dbImage).Error; err != nil {
+			return err
+		}
+		return touchCake(tx, dbImage.CakeID)
+	})
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+	c.deleteImageFiles([]models.CakeImage{dbImage})
+
+	respond(w, r, http.StatusNoContent, nil)
+}
+
+// touchCake increments the version of the cake, whose representation embeds
+// its images, so its ETag changes along with them.
+func touchCake(tx *gorm.DB, cakeID uint) error {
+	return tx.Model(&models.Cake{}).Where("id = ?", cakeID).
+		Update("Version", gorm.Expr("version + 1")).Error
+}
+
+// cakeImage retrieves the image of the IDs on the URL, which must belong to
+// the cake. If the IDs are invalid or the image does not exist, a problem
+// response is written and false is returned.
+func (c *CakeController) cakeImage(w http.ResponseWriter, r *http.Request) (models.CakeImage, bool) {
+	var dbImage models.CakeImage
+
+	vars := mux.Vars(r)
+	cakeID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid cake id") {
+		return dbImage, false
+	}
+	imageID, err := strconv.ParseUint(vars["imageId"], 10, 32)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid image id") {
+		return dbImage, false
+	}
+
+	switch err := c.db.First(&dbImage, "id = ? AND cake_id = ?", imageID, cakeID).Error; err {
+	case nil:
+		return dbImage, true
+
+	case gorm.ErrRecordNotFound:
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Image not found")
+		return dbImage, false
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		return dbImage, false
+	}
+}
+
+// serveImage writes the file stored under the key. Since the keys are never
+// reused, the name of the file is its ETag.
+func (c *CakeController) serveImage(w http.ResponseWriter, r *http.Request, key, contentType string, modified time.Time) {
+	file, err := c.images.Open(key)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Image not found")
+		return
+	case err != nil:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", imageCacheControl)
+	w.Header().Set("ETag", strconv.Quote(path.Base(key)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", modified, file)
+}
+
+// readImage reads an uploaded image, checking its type and dimensions, and
+// generates its thumbnail. The image is decoded once the decoding slot is
+// free, waiting for it until the context is done.
+func readImage(ctx context.Context, header *multipart.FileHeader) (*imageUpload, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	detected := mimetype.Detect(content)
+	if !slices.ContainsFunc(ImageTypes, detected.Is) {
+		return nil, errUnsupportedImage
+	}
+	upload := &imageUpload{
+		content:     content,
+		contentType: detected.String(),
+		extension:   detected.Extension(),
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, errInvalidImage
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, errImageTooLarge
+	}
+	upload.width, upload.height = config.Width, config.Height
+
+	select {
+	case imageDecoding <- struct{}{}:
+		defer func() { <-imageDecoding }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, errInvalidImage
+	}
+	upload.thumbnailType, upload.thumbnailExtension, err = imaging.Encode(
+		&upload.thumbnail, imaging.Thumbnail(img, thumbnailSize), upload.contentType,
+	)
+	return upload, err
+}
+
+// storeImage puts the image and its thumbnail on the storage under new
+// random keys, and returns the image to be saved on the database.
+func (c *CakeController) storeImage(cakeID uint, upload *imageUpload) (models.CakeImage, error) {
+	name := fmt.Sprintf("cakes/%d/%s", cakeID, strings.ToLower(rand.Text()))
+	dbImage := models.CakeImage{
+		CakeID:        cakeID,
+		Key:           name + upload.extension,
+		ContentType:   upload.contentType,
+		Width:         uint(upload.width),
+		Height:        uint(upload.height),
+		Size:          int64(len(upload.content)),
+		ThumbnailKey:  name + "-thumbnail" + upload.thumbnailExtension,
+		ThumbnailType: upload.thumbnailType,
+	}
+
+	if err := c.images.Put(dbImage.Key, bytes.NewReader(upload.content)); err != nil {
+		return dbImage, err
+	}
+	if err := c.images.Put(dbImage.ThumbnailKey, &upload.thumbnail); err != nil {
+		c.deleteImageFiles([]models.CakeImage{dbImage})
+		return dbImage, err
+	}
+	return dbImage, nil
+}
+
+// deleteImageFiles removes the files of the images from the storage. The
+// images are already gone from the database, so failures are only logged.
+func (c *CakeController) deleteImageFiles(dbImages []models.CakeImage) {
+	for _, dbImage := range dbImages {
+		for _, key := range []string{dbImage.Key, dbImage.ThumbnailKey} {
+			if err := c.images.Delete(key); err != nil {
+				log.Println("Cannot delete the image file", key+":", err)
+			}
+		}
+	}
+}
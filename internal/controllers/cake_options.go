@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/mappers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/patch"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// GetCakeOptions retrieves the variant options of a cake by ID, grouped by
+// kind, and encodes them as a JSON response with a 200 OK status code.
+//
+// If the ID is invalid, the function will return a 400 Bad Request response, and if the
+// cake is not found, a 404 Not Found response.
+func (c *CakeController) GetCakeOptions(w http.ResponseWriter, r *http.Request) {
+	dbCake, ok := c.cake(w, r)
+	if !ok {
+		return
+	}
+
+	var dbOptions []models.CakeOption
+	err := c.db.Where("cake_id = ?", dbCake.ID).Order("kind, id").Find(&dbOptions).Error
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+	respond(w, r, http.StatusOK, mappers.CakeOptions(dbOptions))
+}
+
+// CreateCakeOption adds a variant option to the cake of the given ID and
+// returns it as a JSON response with a 201 Created status code. Once a cake
+// offers options of a kind, its orders must pick one of them.
+//
+// If the ID or the request body is invalid, the function will return a 400 Bad Request
+// response, and if the cake is not found, a 404 Not Found response. If the cake already
+// has an option of the same kind and name, or the SKU is taken, it returns a 409 Conflict
+// response.
+func (c *CakeController) CreateCakeOption(w http.ResponseWriter, r *http.Request) {
+	dbCake, ok := c.cake(w, r)
+	if !ok {
+		return
+	}
+
+	var inputOption schemas.CakeOptionInputSchema
+	err := json.NewDecoder(r.Body).Decode(&inputOption)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid input") {
+		return
+	}
+
+	if !errorhandling.CheckValidationError(c.validator.Struct(inputOption), w) {
+		return
+	}
+
+	dbOption := models.CakeOption{
+		CakeID:     dbCake.ID,
+		Kind:       inputOption.Kind,
+		Name:       inputOption.Name,
+		SKU:        strings.ToUpper(inputOption.SKU),
+		Price:      inputOption.Price,
+		PriceDelta: inputOption.PriceDelta,
+	}
+	if !c.checkCakeOption(w, dbOption) {
+		return
+	}
+
+	err = c.db.Create(&dbOption).Error
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+
+	w.Header().Set("ETag", httphelpers.ETag(dbOption.Version))
+	respond(w, r, http.StatusCreated, mappers.CakeOption(dbOption))
+}
+
+// UpdateCakeOption updates a variant option of a cake with a JSON Merge
+// Patch or a JSON Patch and returns it as a JSON response with a 200 OK
+// status code. The orders already placed keep their price and SKU.
+//
+// If the IDs or the request body are invalid, or a price is given to an option other than
+// a size, the function will return a 400 Bad Request response, and if the option is not
+// found, a 404 Not Found response. If the cake already has an option of the same kind and
+// name, or the SKU is taken, it returns a 409 Conflict response.
+//
+// If the If-Match header does not match the ETag of the option, or the option is changed
+// by another request meanwhile, the function will return a 412 Precondition Failed response.
+func (c *CakeController) UpdateCakeOption(w http.ResponseWriter, r *http.Request) {
+	dbOption, ok := c.cakeOption(w, r)
+	if !ok {
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbOption.Version)) {
+		return
+	}
+
+	var inputOption schemas.CakeOptionPatchInputSchema
+	if !errorhandling.CheckPatchError(patch.Decode(r, dbOption, &inputOption), w) {
+		return
+	}
+
+	if !errorhandling.CheckValidationError(c.validator.Struct(inputOption), w) {
+		return
+	}
+
+	updates := patch.Updates(inputOption)
+	patched := dbOption
+	if inputOption.Name.Set {
+		patched.Name = inputOption.Name.Value
+	}
+	if inputOption.SKU.Set {
+		patched.SKU = strings.ToUpper(inputOption.SKU.Value)
+		updates["SKU"] = patched.SKU
+	}
+	if inputOption.Price.Set {
+		var price *uint64
+		if !inputOption.Price.Null {
+			if dbOption.Kind != models.OptionSize {
+				errorhandling.ProblemResponse(w, http.StatusBadRequest, "Only size options replace the price of the cake")
+				return
+			}
+			price = &inputOption.Price.Value
+		}
+		updates["Price"] = price
+	}
+	if !c.checkCakeOption(w, patched) {
+		return
+	}
+
+	updated, err := updateVersioned(c.db, &dbOption, dbOption.Version, updates)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+	if !updated {
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+		return
+	}
+
+	w.Header().Set("ETag", httphelpers.ETag(dbOption.Version))
+	respond(w, r, http.StatusOK, mappers.CakeOption(dbOption))
+}
+
+// DeleteCakeOption removes a variant option of a cake and returns a 204 No
+// Content response. The orders placed with it keep referencing it.
+//
+// If the IDs are invalid, the function will return a 400 Bad Request response, and if
+// the option is not found, a 404 Not Found response.
+//
+// If the If-Match header does not match the ETag of the option, the function will return
+// a 412 Precondition Failed response.
+func (c *CakeController) DeleteCakeOption(w http.ResponseWriter, r *http.Request) {
+	dbOption, ok := c.cakeOption(w, r)
+	if !ok {
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbOption.Version)) {
+		return
+	}
+
+	deleted := c.db.Where("version = ?", dbOption.Version).Delete(&dbOption)
+	switch {
+	case deleted.Error != nil:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+
+	case deleted.RowsAffected == 0:
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+
+	default:
+		respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// cake retrieves the cake of the ID on the URL, archived or not. If the ID is
+// invalid or the cake does not exist, a problem response is written and false
+// is returned.
+func (c *CakeController) cake(w http.ResponseWriter, r *http.Request) (models.Cake, bool) {
+	var dbCake models.Cake
+
+	cakeID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid cake id") {
+		return dbCake, false
+	}
+
+	switch err := c.db.First(&dbCake, cakeID).Error; err {
+	case nil:
+		return dbCake, true
+
+	case gorm.ErrRecordNotFound:
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Cake not found")
+		return dbCake, false
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		return dbCake, false
+	}
+}
+
+// cakeOption retrieves the option of the IDs on the URL, which must belong
+// to the cake. If the IDs are invalid or the option does not exist, a problem
+// response is written and false is returned.
+func (c *CakeController) cakeOption(w http.ResponseWriter, r *http.Request) (models.CakeOption, bool) {
+	var dbOption models.CakeOption
+
+	vars := mux.Vars(r)
+	cakeID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid cake id") {
+		return dbOption, false
+	}
+	optionID, err := strconv.ParseUint(vars["optionId"], 10, 32)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid option id") {
+		return dbOption, false
+	}
+
+	switch err := c.db.First(&dbOption, "id = ? AND cake_id = ?", optionID, cakeID).Error; err {
+	case nil:
+		return dbOption, true
+
+	case gorm.ErrRecordNotFound:
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Option not found")
+		return dbOption, false
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		return dbOption, false
+	}
+}
+
+// checkCakeOption checks that no other option of the cake has the kind and
+// name of the given one, and that no other option, even a deleted one, has
+// its SKU. Otherwise a 409 Conflict problem is written and false is returned.
+func (c *CakeController) checkCakeOption(w http.ResponseWriter, dbOption models.CakeOption) bool {
+	duplicated := c.db.First(
+		&models.CakeOption{}, "cake_id = ? AND kind = ? AND name = ? AND id <> ?",
+		dbOption.CakeID, dbOption.Kind, dbOption.Name, dbOption.ID,
+	)
+	if duplicated.RowsAffected > 0 {
+		errorhandling.ProblemResponse(w, http.StatusConflict, "Option already exists")
+		return false
+	}
+
+	taken := c.db.Unscoped().First(&models.CakeOption{}, "sku = ? AND id <> ?", dbOption.SKU, dbOption.ID)
+	if taken.RowsAffected > 0 {
+		errorhandling.ProblemResponse(w, http.StatusConflict, "SKU already exists")
+		return false
+	}
+	return true
+}
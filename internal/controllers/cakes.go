@@ -1,45 +1,146 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/LeandroDeJesus-S/confectionery/internal/mappers"
 	"github.com/LeandroDeJesus-S/confectionery/internal/models"
 	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+	"github.com/LeandroDeJesus-S/confectionery/internal/storage"
 	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
 	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/patch"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
+// CakeDeletePolicy defines what happens when a cake referenced by orders is deleted
+type CakeDeletePolicy string
+
+const (
+	// CakeDeleteBlock refuses to delete cakes referenced by orders
+	CakeDeleteBlock CakeDeletePolicy = "block"
+	// CakeDeleteArchive hides deleted cakes from the catalog, keeping them for the orders
+	CakeDeleteArchive CakeDeletePolicy = "archive"
+	// CakeDeleteCascade deletes the orders along with the cake
+	CakeDeleteCascade CakeDeletePolicy = "cascade"
+)
+
 type CakeController struct {
-	db        *gorm.DB
-	validator *validator.Validate
+	db           *gorm.DB
+	validator    *validator.Validate
+	deletePolicy CakeDeletePolicy
+	images       storage.Storage
 }
 
-func NewCakeController(db *gorm.DB, validator *validator.Validate) *CakeController {
-	return &CakeController{db: db, validator: validator}
+// NewCakeController initializes the CakeController structure, keeping the
+// images of the cakes on the given storage. The deletion policy is read from
+// the CAKE_DELETE_POLICY environment variable, which defaults to "block".
+func NewCakeController(db *gorm.DB, validator *validator.Validate, images storage.Storage) *CakeController {
+	policy := CakeDeletePolicy(os.Getenv("CAKE_DELETE_POLICY"))
+	switch policy {
+	case "":
+		policy = CakeDeleteBlock
+	case CakeDeleteBlock, CakeDeleteArchive, CakeDeleteCascade:
+		break
+	default:
+		log.Fatal("Invalid CAKE_DELETE_POLICY: ", policy)
+	}
+
+	return &CakeController{db: db, validator: validator, deletePolicy: policy, images: images}
 }
 
-// GetCakes retrieves all the cakes from the database,
+// GetCakes retrieves the cakes of the catalog from the database,
 // converts them to the output schema, and encodes the result
-// as a JSON response.
+// as a JSON response. The catalog only lists the cakes orderable today, or on
+// the date of ?availableOn=, as described on orderableOn, unless ?include=unavailable
+// is given, and archived cakes are only listed with ?include=archived. The images of the
+// cakes are always embedded and their options with ?include=options, ?category=
+// and ?tags= list only the cakes of the category and with all the given tags,
+// ?excludeAllergens= leaves out the cakes holding any of the given allergens, and ?fields=
+// selects the fields returned.
 func (c *CakeController) GetCakes(w http.ResponseWriter, r *http.Request) {
-	var dbCakes []models.Cake
-	c.db.Find(&dbCakes)
-
-	outputCakes := make([]schemas.CakeOutputSchema, 0)
-	for _, dbCake := range dbCakes {
-		outputCake := schemas.CakeOutputSchema{
-			ID:    dbCake.ID,
-			Name:  dbCake.Name,
-			Price: dbCake.Price,
+	query, includeArchived, includeUnavailable := c.db, false, false
+	for _, include := range httphelpers.QueryList(r, "include") {
+		switch include {
+		case "archived":
+			includeArchived = true
+		case "unavailable":
+			includeUnavailable = true
+		case "options", "images":
+			query = query.Preload(cakeIncludes[include], func(tx *gorm.DB) *gorm.DB {
+				return tx.Order("id")
+			})
+		default:
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid include parameter")
+			return
+		}
+	}
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	if !includeUnavailable {
+		day := storeToday()
+		if availableOn := r.URL.Query().Get("availableOn"); availableOn != "" {
+			var err error
+			day, err = time.Parse(time.DateOnly, availableOn)
+			if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid availableOn parameter") {
+				return
+			}
 		}
-		outputCakes = append(outputCakes, outputCake)
+		query = query.Scopes(orderableOn(day))
+	}
+
+	if category := r.URL.Query().Get("category"); category != "" {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if c.validator.Var(category, "tag") != nil {
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid category parameter")
+			return
+		}
+		query = query.Where("category = ?", category)
+	}
+
+	// the tags are stored as JSON arrays of lower case strings, and the valid
+	// ones hold no characters to escape
+	for _, tag := range tagList(httphelpers.QueryList(r, "tags")) {
+		if c.validator.Var(tag, "tag") != nil {
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid tags parameter")
+			return
+		}
+		query = query.Where("tags LIKE ?", `%"`+tag+`"%`)
+	}
+
+	// the allergens are stored as JSON arrays of the names on models.Allergens
+	for _, allergen := range httphelpers.QueryList(r, "excludeAllergens") {
+		if c.validator.Var(allergen, "allergen") != nil {
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid excludeAllergens parameter")
+			return
+		}
+		query = query.Where("allergens NOT LIKE ?", `%"`+allergen+`"%`)
+	}
+
+	fields, ok := parseFieldset(w, r, c.db, &models.Cake{}, schemas.CakeOutputSchema{})
+	if !ok {
+		return
+	}
+	if fields.has("images") {
+		query = preloadImages(query)
 	}
-	httphelpers.JsonResponse(w, http.StatusOK, outputCakes)
+
+	writeList(w, r, fields, fields.selectColumns(query, "id"), func(dbCakes []models.Cake) ([]schemas.CakeOutputSchema, error) {
+		return mappers.Cakes(dbCakes), nil
+	})
 }
 
 // GetCake retrieves a cake by ID from the database, converts it
@@ -52,6 +153,10 @@ func (c *CakeController) GetCakes(w http.ResponseWriter, r *http.Request) {
 // If the customer is successfully retrieved, the function will return the customer details as a
 // JSON response with a 200 OK status code. If there are any server errors, it
 // returns a 500 Internal Server Error response.
+//
+// The response carries the ETag of its representation, and a 304 Not Modified response is returned
+// when it matches the If-None-Match header. The fields returned can be selected with ?fields=,
+// and the images of the cake are always embedded and its options with ?include=options.
 func (c *CakeController) GetCake(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -60,39 +165,34 @@ func (c *CakeController) GetCake(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query, ok := withIncludes(w, r, c.db, cakeIncludes)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseFieldset(w, r, c.db, &models.Cake{}, schemas.CakeOutputSchema{})
+	if !ok {
+		return
+	}
+	if fields.has("images") {
+		query = preloadImages(query)
+	}
+
 	var dbCake models.Cake
-	result := c.db.First(&dbCake, id)
+	result := fields.selectColumns(query, "id", "version").First(&dbCake, id)
 
 	switch result.Error {
 	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Internal server error"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
 		return
 
 	case gorm.ErrRecordNotFound:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusNotFound,
-			schemas.Message{
-				Code:   http.StatusNotFound,
-				Detail: []string{"Cake not found"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Cake not found")
 		return
 
 	case nil:
-		outputCake := schemas.CakeOutputSchema{
-			ID:    dbCake.ID,
-			Name:  dbCake.Name,
-			Price: dbCake.Price,
-		}
-		httphelpers.JsonResponse(w, http.StatusOK, outputCake)
+		outputCake := mappers.Cake(dbCake)
+		fields.writeVersion(w, r, dbCake.Version, outputCake)
 		return
 	}
 }
@@ -112,48 +212,54 @@ func (c *CakeController) CreateCake(w http.ResponseWriter, r *http.Request) {
 	}
 
 	isValidStruct := c.validator.Struct(inputCake)
-	if !errorhandling.CheckOrHttpError(isValidStruct, w, http.StatusBadRequest, "Invalid input") {
+	if !errorhandling.CheckValidationError(isValidStruct, w) {
 		return
 	}
 
 	found := c.db.First(&models.Cake{}, "name = ?", inputCake.Name)
 	if found.RowsAffected > 0 {
-		m := schemas.Message{Code: http.StatusBadRequest, Detail: []string{"Cake already exists"}}
-		httphelpers.JsonResponse(w, http.StatusBadRequest, m)
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Cake already exists")
 		return
 	}
 
-	dbCake := models.Cake{
-		Name:  inputCake.Name,
-		Price: inputCake.Price,
+	dbCake := cakeModel(inputCake)
+	if !validAvailability(dbCake) {
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "The availability ends before it starts")
+		return
 	}
 
 	result := c.db.Create(&dbCake)
 
 	switch result.Error {
 	case nil:
-		outputCake := schemas.CakeOutputSchema{
-			ID:    dbCake.ID,
-			Name:  dbCake.Name,
-			Price: dbCake.Price,
-		}
-		httphelpers.JsonResponse(w, http.StatusCreated, outputCake)
+		outputCake := mappers.Cake(dbCake)
+		respond(w, r, http.StatusCreated, outputCake)
 
 	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Internal server error"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
 	}
 }
 
+// CreateCakes creates every cake of the JSON array of the request body and
+// returns the result of each one, created cakes getting a 201 Created status
+// and the created cake as data. The items are validated and rejected like on
+// CreateCake, and they are processed as described on runBulk.
+func (c *CakeController) CreateCakes(w http.ResponseWriter, r *http.Request) {
+	var inputCakes []schemas.CakeInputSchema
+	hasDecoded := json.NewDecoder(r.Body).Decode(&inputCakes)
+	if !errorhandling.CheckOrHttpError(hasDecoded, w, http.StatusBadRequest, "Invalid input") {
+		return
+	}
+
+	runBulk(w, r, c.db, len(inputCakes), http.StatusCreated, func(tx *gorm.DB, i int) schemas.BulkItemResult {
+		return createCakeItem(w, tx, c.validator, inputCakes[i])
+	})
+}
+
 // UpdateCake updates a cake by ID in the database.
 //
-// It expects the request body to be a JSON object with optional "name" and "price" fields.
+// It expects the request body to be a JSON Merge Patch with optional "name" and "price" fields,
+// or a JSON Patch sent as application/json-patch+json.
 // If the request body is invalid, the function will return a 400 Bad Request response.
 // If the ID is invalid, the function will return a 400 Bad Request response.
 //
@@ -162,6 +268,9 @@ func (c *CakeController) CreateCake(w http.ResponseWriter, r *http.Request) {
 // If the customer is successfully updated, the function will return the updated customer details as a
 // JSON response with a 200 OK status code. If there are any server errors, it
 // returns a 500 Internal Server Error response.
+//
+// If the If-Match header does not match the ETag of the cake, or the cake is changed by
+// another request meanwhile, the function will return a 412 Precondition Failed response.
 func (c *CakeController) UpdateCake(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	cakeId, err := strconv.ParseUint(vars["id"], 10, 32)
@@ -171,21 +280,119 @@ func (c *CakeController) UpdateCake(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var dbCake models.Cake
+	result := preloadImages(c.db).First(&dbCake, cakeId)
+
+	switch result.Error {
+	case nil:
+		break
+
+	case gorm.ErrRecordNotFound:
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Cake not found")
+		return
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbCake.Version)) {
+		return
+	}
+
 	var inputCake schemas.CakePatchInputSchema
-	hasDecoded := json.NewDecoder(r.Body).Decode(&inputCake)
-	if !errorhandling.CheckOrHttpError(hasDecoded, w, http.StatusBadRequest, "Invalid input") {
+	if !errorhandling.CheckPatchError(patch.Decode(r, dbCake, &inputCake), w) {
 		return
 	}
 
-	var dbCake models.Cake
+	if !errorhandling.CheckValidationError(c.validator.Struct(inputCake), w) {
+		return
+	}
+
+	if inputCake.Name.Set {
+		duplicated := c.db.First(&models.Cake{}, "name = ? AND id <> ?", inputCake.Name.Value, dbCake.ID)
+		if duplicated.RowsAffected > 0 {
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Cake already exists")
+			return
+		}
+	}
 
-	duplicated := c.db.First(&dbCake, "name = ?", inputCake.Name)
-	if duplicated.RowsAffected > 0 {
-		m := schemas.Message{Code: http.StatusBadRequest, Detail: []string{"Cake already exists"}}
-		httphelpers.JsonResponse(w, http.StatusBadRequest, m)
+	updates := patch.Updates(inputCake)
+	if inputCake.Allergens.Set {
+		updates["Allergens"] = models.List[string](inputCake.Allergens.Value)
+	}
+	if inputCake.Nutrition.Set {
+		// removed with an untyped nil, since gorm leaves the model untouched
+		// when given a nil pointer to a scanner
+		updates["Nutrition"] = nil
+		if inputCake.Nutrition.Value != nil {
+			updates["Nutrition"] = (*models.NutritionFacts)(inputCake.Nutrition.Value)
+		}
+	}
+	if inputCake.PersonalizationSchema.Set {
+		updates["PersonalizationSchema"] = jsonDocument(inputCake.PersonalizationSchema.Value)
+	}
+	if inputCake.Category.Set {
+		updates["Category"] = strings.ToLower(strings.TrimSpace(inputCake.Category.Value))
+	}
+	if inputCake.Tags.Set {
+		updates["Tags"] = tagList(inputCake.Tags.Value)
+	}
+	if inputCake.Weekdays.Set {
+		updates["Weekdays"] = weekdays(inputCake.Weekdays.Value)
+	}
+	patched := dbCake
+	if inputCake.AvailableFrom.Set {
+		patched.AvailableFrom = optionalText(inputCake.AvailableFrom.Value)
+		updates["AvailableFrom"] = patched.AvailableFrom
+	}
+	if inputCake.AvailableUntil.Set {
+		patched.AvailableUntil = optionalText(inputCake.AvailableUntil.Value)
+		updates["AvailableUntil"] = patched.AvailableUntil
+	}
+	if !validAvailability(patched) {
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "The availability ends before it starts")
+		return
+	}
+
+	updated, err := updateVersioned(c.db, &dbCake, dbCake.Version, updates)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+	if !updated {
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
 		return
 	}
+	w.Header().Set("ETag", httphelpers.ETag(dbCake.Version))
 
+	out := mappers.Cake(dbCake)
+	respond(w, r, http.StatusOK, out)
+}
+
+// DeleteCake deletes a cake by ID from the database and returns a 204 No Content response.
+//
+// If the ID is invalid, the function will return a 400 Bad Request response.
+//
+// If the customer is not found, the function will return a 404 Not Found response.
+//
+// If the customer is successfully deleted, the function will return a 204 No Content response.
+//
+// If the If-Match header does not match the ETag of the cake, the function will return
+// a 412 Precondition Failed response.
+//
+// Cakes referenced by orders are handled according to the deletion policy: "block" returns
+// a 409 Conflict response listing the orders, "archive" hides the cake from the catalog
+// until it is restored and "cascade" deletes the orders along with the cake.
+func (c *CakeController) DeleteCake(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cakeId, err := strconv.ParseUint(vars["id"], 10, 32)
+
+	castCheck := errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid cake id")
+	if !castCheck {
+		return
+	}
+
+	var dbCake models.Cake
 	result := c.db.First(&dbCake, cakeId)
 
 	switch result.Error {
@@ -193,46 +400,90 @@ func (c *CakeController) UpdateCake(w http.ResponseWriter, r *http.Request) {
 		break
 
 	case gorm.ErrRecordNotFound:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusNotFound,
-			schemas.Message{
-				Code:   http.StatusNotFound,
-				Detail: []string{"Cake not found"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Cake not found")
+		return
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbCake.Version)) {
+		return
+	}
+
+	var orderIDs []uint
+	err = c.db.Unscoped().Model(&models.Order{}).Where("cake_id = ?", dbCake.ID).Order("id").Pluck("id", &orderIDs).Error
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
 		return
+	}
+
+	// the files of the images are removed once the cake is gone
+	var dbImages []models.CakeImage
+	err = c.db.Where("cake_id = ?", dbCake.ID).Find(&dbImages).Error
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+
+	switch {
+	case len(orderIDs) == 0:
+		err = c.deleteCake(c.db, dbCake)
+
+	case c.deletePolicy == CakeDeleteArchive:
+		dbImages = nil
+		var updated bool
+		updated, err = updateVersioned(c.db, &dbCake, dbCake.Version, map[string]any{"ArchivedAt": time.Now()})
+		if err == nil && !updated {
+			err = errVersionConflict
+		}
+
+	case c.deletePolicy == CakeDeleteCascade:
+		err = c.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Where("cake_id = ?", dbCake.ID).Delete(&models.Order{}).Error; err != nil {
+				return err
+			}
+			return c.deleteCake(tx, dbCake)
+		})
 
 	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Internal server error"},
-			},
-		)
+		errorhandling.WriteProblem(w, schemas.Problem{
+			Type:     errorhandling.ProblemType(http.StatusConflict),
+			Title:    http.StatusText(http.StatusConflict),
+			Status:   http.StatusConflict,
+			Detail:   "Cake is referenced by orders",
+			OrderIDs: orderIDs,
+		})
 		return
 	}
 
-	c.db.Model(&dbCake).Updates(inputCake)
+	switch {
+	case err == nil:
+		c.deleteImageFiles(dbImages)
+		respond(w, r, http.StatusNoContent, nil)
+
+	case errors.Is(err, errVersionConflict):
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
 
-	out := schemas.CakeOutputSchema{
-		ID:    dbCake.ID,
-		Name:  dbCake.Name,
-		Price: dbCake.Price,
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		// an order was placed for the cake meanwhile
+		errorhandling.ProblemResponse(w, http.StatusConflict, "Cake is referenced by orders")
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
 	}
-	httphelpers.JsonResponse(w, http.StatusOK, out)
 }
 
-// DeleteCake deletes a cake by ID from the database and returns a 204 No Content response.
+// RestoreCake brings an archived cake back to the catalog and returns it as a
+// JSON response with a 200 OK status code.
 //
 // If the ID is invalid, the function will return a 400 Bad Request response.
 //
-// If the customer is not found, the function will return a 404 Not Found response.
+// If the cake is not found, the function will return a 404 Not Found response, and if
+// it is not archived, a 409 Conflict response.
 //
-// If the customer is successfully deleted, the function will return a 204 No Content response.
-func (c *CakeController) DeleteCake(w http.ResponseWriter, r *http.Request) {
+// If the If-Match header does not match the ETag of the cake, the function will return
+// a 412 Precondition Failed response.
+func (c *CakeController) RestoreCake(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	cakeId, err := strconv.ParseUint(vars["id"], 10, 32)
 
@@ -242,31 +493,165 @@ func (c *CakeController) DeleteCake(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var dbCake models.Cake
-	result := c.db.First(&dbCake, cakeId)
+	result := preloadImages(c.db).First(&dbCake, cakeId)
 
 	switch result.Error {
 	case nil:
-		c.db.Delete(&dbCake)
-		httphelpers.JsonResponse(w, http.StatusNoContent, nil)
+		break
 
 	case gorm.ErrRecordNotFound:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusNotFound,
-			schemas.Message{
-				Code:   http.StatusNotFound,
-				Detail: []string{"Cake not found"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Cake not found")
+		return
 
 	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Internal server error"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if dbCake.ArchivedAt == nil {
+		errorhandling.ProblemResponse(w, http.StatusConflict, "Cake is not archived")
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbCake.Version)) {
+		return
+	}
+
+	updated, err := updateVersioned(c.db, &dbCake, dbCake.Version, map[string]any{"ArchivedAt": nil})
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+	if !updated {
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+		return
+	}
+	w.Header().Set("ETag", httphelpers.ETag(dbCake.Version))
+
+	respond(w, r, http.StatusOK, mappers.Cake(dbCake))
+}
+
+// createCakeItem validates and creates a cake of a bulk request or import,
+// rejecting it like CreateCake.
+func createCakeItem(
+	w http.ResponseWriter, tx *gorm.DB, validator *validator.Validate, inputCake schemas.CakeInputSchema,
+) schemas.BulkItemResult {
+	if err := validator.Struct(inputCake); err != nil {
+		return rejectedItem(errorhandling.NewValidationProblem(w, err))
+	}
+
+	found := tx.First(&models.Cake{}, "name = ?", inputCake.Name)
+	if found.RowsAffected > 0 {
+		return rejectedItem(errorhandling.NewProblem(w, http.StatusBadRequest, "Cake already exists"))
+	}
+
+	dbCake := cakeModel(inputCake)
+	if !validAvailability(dbCake) {
+		return rejectedItem(errorhandling.NewProblem(w, http.StatusBadRequest, "The availability ends before it starts"))
+	}
+	if err := tx.Create(&dbCake).Error; err != nil {
+		return rejectedItem(errorhandling.NewProblem(w, http.StatusInternalServerError, "Internal server error"))
+	}
+	return schemas.BulkItemResult{ID: dbCake.ID, Status: http.StatusCreated, Data: mappers.Cake(dbCake)}
+}
+
+// cakeModel converts the input schema to the cake stored on the database.
+func cakeModel(inputCake schemas.CakeInputSchema) models.Cake {
+	dbCake := models.Cake{
+		Name:      inputCake.Name,
+		Price:     inputCake.Price,
+		Allergens: inputCake.Allergens,
+		Nutrition: (*models.NutritionFacts)(inputCake.Nutrition),
+
+		PersonalizationSchema: jsonDocument(inputCake.PersonalizationSchema),
+
+		Category:       strings.ToLower(strings.TrimSpace(inputCake.Category)),
+		Tags:           tagList(inputCake.Tags),
+		Available:      inputCake.Available,
+		AvailableFrom:  inputCake.AvailableFrom,
+		AvailableUntil: inputCake.AvailableUntil,
+		Weekdays:       weekdays(inputCake.Weekdays),
+	}
+	return dbCake
+}
+
+// weekdays sorts the weekdays in the order of the week.
+func weekdays(days []string) models.List[string] {
+	sorted := make(models.List[string], 0, len(days))
+	for _, day := range models.Weekdays {
+		if slices.Contains(days, day) {
+			sorted = append(sorted, day)
+		}
+	}
+	return sorted
+}
+
+// validAvailability reports whether the cake does not stop being available
+// before it starts.
+func validAvailability(dbCake models.Cake) bool {
+	return dbCake.AvailableFrom == nil || dbCake.AvailableUntil == nil || *dbCake.AvailableFrom <= *dbCake.AvailableUntil
+}
+
+// defaultStoreTimezone is the time zone of the store when STORE_TIMEZONE is
+// not set
+const defaultStoreTimezone = "America/Sao_Paulo"
+
+// StoreLocation returns the time zone of the store, which tells the day the
+// orders placed without a due date are for, read once from the
+// STORE_TIMEZONE environment variable as an IANA name such as
+// "America/Sao_Paulo", the default. An invalid name stops the server.
+var StoreLocation = sync.OnceValue(func() *time.Location {
+	name := os.Getenv("STORE_TIMEZONE")
+	if name == "" {
+		name = defaultStoreTimezone
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Fatal("Invalid STORE_TIMEZONE: ", name)
+	}
+	return location
+})
+
+// storeToday returns the current time on the time zone of the store, whose
+// date is the day of the cakes listed, searched and ordered for today.
+func storeToday() time.Time {
+	return time.Now().In(StoreLocation())
+}
+
+// orderableOn limits the query to the cakes that can be ordered for the given
+// day: the available ones whose dates include the day and that are offered
+// on its weekday. Archived cakes are left to the caller.
+func orderableOn(day time.Time) func(*gorm.DB) *gorm.DB {
+	date := day.Format(time.DateOnly)
+	return func(tx *gorm.DB) *gorm.DB {
+		// the weekdays are stored as JSON arrays of the names on models.Weekdays
+		return tx.Where("cakes.available").
+			Where("cakes.available_from IS NULL OR cakes.available_from <= ?", date).
+			Where("cakes.available_until IS NULL OR cakes.available_until >= ?", date).
+			Where("cakes.weekdays = '[]' OR cakes.weekdays LIKE ?", `%"`+models.Weekdays[day.Weekday()]+`"%`)
+	}
+}
+
+// jsonDocument converts a JSON document of a request to the form it is stored
+// in, without insignificant spaces, where null is an empty document.
+func jsonDocument(raw json.RawMessage) models.JSON {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return models.JSON(raw)
+	}
+	return models.JSON(compact.Bytes())
+}
+
+// deleteCake deletes the cake unless it was changed since it was read.
+func (c *CakeController) deleteCake(tx *gorm.DB, dbCake models.Cake) error {
+	deleted := tx.Where("version = ?", dbCake.Version).Delete(&dbCake)
+	if deleted.Error != nil {
+		return deleted.Error
+	}
+	if deleted.RowsAffected == 0 {
+		return errVersionConflict
 	}
+	return nil
 }
@@ -2,19 +2,32 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/LeandroDeJesus-S/confectionery/internal/config/validation"
+	"github.com/LeandroDeJesus-S/confectionery/internal/mappers"
 	"github.com/LeandroDeJesus-S/confectionery/internal/models"
 	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
 	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
 	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/patch"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
+var (
+	errEmailExists = errors.New("email already exists")
+	errCPFExists   = errors.New("cpf already exists")
+	// errNoContact is returned for customers left without an email and a phone
+	errNoContact = errors.New("customer without contact")
+)
+
 type CustomerController struct {
 	db        *gorm.DB
 	validator *validator.Validate
@@ -27,32 +40,69 @@ func NewCustomerController(db *gorm.DB, validator *validator.Validate) *Customer
 
 // GetAllCustomers retrieves all the active customers from the database,
 // converts them to the output schema, and encodes the result
-// as a JSON response.
+// as a JSON response. Deleted customers are listed too with ?includeDeleted=true,
+// ?tags= lists only the customers with all the given tags, and ?fields= selects
+// the fields returned.
 func (c *CustomerController) GetAllCustomers(w http.ResponseWriter, r *http.Request) {
-	var dbCustomers []models.Customer
-	outputCustomers := make([]schemas.CustomerOutputSchema, 0)
+	includeDeleted, err := httphelpers.QueryBool(r, "includeDeleted")
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid includeDeleted parameter") {
+		return
+	}
 
-	c.db.Find(&dbCustomers, "active = ?", true)
+	query := c.db.Where("active = ?", true)
+	if includeDeleted {
+		query = c.db
+	}
 
-	for _, dbCustomer := range dbCustomers {
-		outputCustomer := schemas.CustomerOutputSchema{
-			ID:    dbCustomer.ID,
-			Fname: dbCustomer.Fname,
-			Lname: dbCustomer.Lname,
-			Email: dbCustomer.Email,
+	// the tags are stored as JSON arrays of lower case strings, and the valid
+	// ones hold no characters to escape
+	for _, tag := range tagList(httphelpers.QueryList(r, "tags")) {
+		if c.validator.Var(tag, "tag") != nil {
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid tags parameter")
+			return
 		}
-		outputCustomers = append(outputCustomers, outputCustomer)
+		query = query.Where("tags LIKE ?", `%"`+tag+`"%`)
 	}
 
-	httphelpers.JsonResponse(
-		w,
-		http.StatusOK,
-		outputCustomers,
-	)
+	query, ok := withIncludes(w, r, query, customerIncludes)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseFieldset(w, r, c.db, &models.Customer{}, schemas.CustomerOutputSchema{})
+	if !ok {
+		return
+	}
+
+	writeList(w, r, fields, fields.selectColumns(query, "id"), func(dbCustomers []models.Customer) ([]schemas.CustomerOutputSchema, error) {
+		return customerOutputs(c.db, dbCustomers)
+	})
+}
+
+// GetCustomersTrash retrieves the deleted customers from the database and
+// encodes them as a JSON response.
+func (c *CustomerController) GetCustomersTrash(w http.ResponseWriter, r *http.Request) {
+	query, ok := withIncludes(w, r, c.db, customerIncludes)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseFieldset(w, r, c.db, &models.Customer{}, schemas.CustomerOutputSchema{})
+	if !ok {
+		return
+	}
+
+	query = fields.selectColumns(query, "id").Where("active = ?", false)
+	writeList(w, r, fields, query, func(dbCustomers []models.Customer) ([]schemas.CustomerOutputSchema, error) {
+		return customerOutputs(c.db, dbCustomers)
+	})
 }
 
 // GetCustomer retrieves an active customer by ID from the database, converts it
-// to the output schema, and encodes the result as a JSON response.
+// to the output schema, and encodes the result as a JSON response. The response
+// carries the ETag of its representation, and a 304 Not Modified response is returned
+// when it matches the If-None-Match header. The fields returned can be
+// selected with ?fields=.
 func (c *CustomerController) GetCustomer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -61,116 +111,83 @@ func (c *CustomerController) GetCustomer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	query, ok := withIncludes(w, r, c.db, customerIncludes)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseFieldset(w, r, c.db, &models.Customer{}, schemas.CustomerOutputSchema{})
+	if !ok {
+		return
+	}
+
 	var dbCustomer models.Customer
-	result := c.db.First(&dbCustomer, "id = ? AND active = ?", id, true)
+	result := fields.selectColumns(query, "id", "version").First(&dbCustomer, "id = ? AND active = ?", id, true)
 
 	switch result.Error {
 	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Internal server error"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
 		return
 
 	case gorm.ErrRecordNotFound:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusNotFound,
-			schemas.Message{
-				Code:   http.StatusNotFound,
-				Detail: []string{"Customer not found"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Customer not found")
 		return
 
 	case nil:
 		break
 	}
 
-	outputCustomer := schemas.CustomerOutputSchema{
-		ID:    dbCustomer.ID,
-		Fname: dbCustomer.Fname,
-		Lname: dbCustomer.Lname,
-		Email: dbCustomer.Email,
+	stats, err := customerStats(c.db, dbCustomer.ID)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
 	}
-	httphelpers.JsonResponse(
-		w,
-		http.StatusOK,
-		outputCustomer,
-	)
+
+	outputCustomer := mappers.Customer(dbCustomer, stats[dbCustomer.ID])
+	fields.writeVersion(w, r, dbCustomer.Version, outputCustomer)
 }
 
 // CreateCustomer creates a new customer in the database and returns it as a JSON response.
 //
-// If the request body is invalid or the email already exists, the function will return an appropriate
-// HTTP status code and a JSON response with an error message.
+// If the request body is invalid, the customer has neither an email nor a phone, or the email
+// or the CPF already exists, the function will return an appropriate HTTP status code and a
+// JSON response with an error message.
 //
 // If the customer is successfully created, the function will return the created customer as a JSON
 // response with the HTTP status code 201 Created.
 func (c *CustomerController) CreateCustomer(w http.ResponseWriter, r *http.Request) {
 	var inpCustomer schemas.CustomerInputSchema
 	err := json.NewDecoder(r.Body).Decode(&inpCustomer)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid input") {
+		return
 	}
 
 	err = c.validator.Struct(inpCustomer)
-	if err != nil {
-		httphelpers.JsonResponse(
-			w,
-			http.StatusBadRequest,
-			&schemas.Message{
-				Code:   http.StatusBadRequest,
-				Detail: strings.Split(err.Error(), "\n"),
-			},
-		)
+	if !errorhandling.CheckValidationError(err, w) {
 		return
 	}
 
-	var emailExists *models.Customer
-	if c.db.First(&emailExists, "email = ?", inpCustomer.Email).RowsAffected > 0 {
-		httphelpers.JsonResponse(
-			w,
-			http.StatusConflict,
-			&schemas.Message{
-				Code:   http.StatusConflict,
-				Detail: []string{"Email already exists"},
-			},
-		)
+	dbCustomer := customerModel(inpCustomer)
+	if err := checkUniqueContacts(c.db, dbCustomer); err != nil {
+		status, detail := customerContactsProblem(err)
+		errorhandling.ProblemResponse(w, status, detail)
 		return
 	}
 
-	dbCustomer := models.Customer{
-		Fname: inpCustomer.Fname,
-		Lname: inpCustomer.Lname,
-		Email: inpCustomer.Email,
-	}
 	res := c.db.Create(&dbCustomer)
+	if errors.Is(res.Error, gorm.ErrDuplicatedKey) {
+		status, detail := customerContactsProblem(contactsError(c.db, dbCustomer, res.Error))
+		errorhandling.ProblemResponse(w, status, detail)
+		return
+	}
 	if res.Error != nil {
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			&schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Error creating customer: " + res.Error.Error()},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Error creating customer")
 		return
 	}
 
-	outCustomer := &schemas.CustomerOutputSchema{
-		ID:    dbCustomer.ID,
-		Fname: dbCustomer.Fname,
-		Lname: dbCustomer.Lname,
-		Email: dbCustomer.Email,
-	}
+	outCustomer := mappers.Customer(dbCustomer, &schemas.CustomerStats{})
 
-	httphelpers.JsonResponse(
-		w,
+	respond(
+		w, r,
 		http.StatusCreated,
 		outCustomer,
 	)
@@ -179,81 +196,131 @@ func (c *CustomerController) CreateCustomer(w http.ResponseWriter, r *http.Reque
 // UpdateCustomer updates an existing customer's details by ID from the database.
 // It parses the customer ID from the URL, verifies its validity, and retrieves
 // the existing customer record. If the customer is not found, it returns a 404
-// Not Found response. The function then decodes the request body for partial
-// updates, validates the input, and checks for unique email constraints.
-// If any validation fails, it sends a 400 Bad Request response. Upon successful
+// Not Found response. The function then decodes the request body, a JSON Merge
+// Patch or a JSON Patch, validates the input, and checks for unique email and CPF
+// constraints. If any validation fails, or the customer would be left without an
+// email and a phone, it sends a 400 Bad Request response, and a 409 Conflict one
+// for a duplicated email or CPF. Upon successful
 // update, it saves the changes and returns the updated customer details as a
 // JSON response with a 200 OK status code. If there are any server errors, it
-// returns a 500 Internal Server Error response.
+// returns a 500 Internal Server Error response. If the If-Match header does not
+// match the ETag of the customer, or the customer is changed by another request
+// meanwhile, it returns a 412 Precondition Failed response.
 func (c *CustomerController) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	customerID, err := strconv.Atoi(vars["id"])
 
-	isValidNumber := errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest)
+	isValidNumber := errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid customer ID")
 	if !isValidNumber {
 		return
 	}
 
 	if customerID <= 0 {
-		httphelpers.JsonResponse(
-			w,
-			http.StatusBadRequest,
-			&schemas.Message{
-				Code:   http.StatusBadRequest,
-				Detail: []string{"Invalid customer ID"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid customer ID")
 		return
 	}
 
 	var dbCustomer models.Customer
 	res := c.db.First(&dbCustomer, customerID)
-	errMsg := &schemas.Message{
-		Code:   http.StatusInternalServerError,
-		Detail: []string{"Unexpected error"},
-	}
 
 	switch res.Error {
 	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			errMsg,
-		)
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Unexpected error")
 		return
 
 	case nil:
 		break
 
 	case gorm.ErrRecordNotFound:
-		errMsg.Code, errMsg.Detail = http.StatusNotFound, []string{"Customer not found"}
-		httphelpers.JsonResponse(w, http.StatusNotFound, errMsg)
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Customer not found")
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbCustomer.Version)) {
 		return
 	}
 
 	var input schemas.CustomerPatchInputSchema
-	deacoded := json.NewDecoder(r.Body).Decode(&input)
+	if !errorhandling.CheckPatchError(patch.Decode(r, dbCustomer, &input), w) {
+		return
+	}
+
+	if !errorhandling.CheckValidationError(c.validator.Struct(input), w) {
+		return
+	}
+
+	updates := patch.Updates(input)
+	if input.Email.Set {
+		updates["Email"] = optionalText(input.Email.Value)
+	}
+	if input.Phone.Set {
+		phone, _ := validation.NormalizePhone(input.Phone.Value)
+		updates["Phone"] = optionalText(phone)
+	}
+	if input.CPF.Set {
+		updates["CPF"] = optionalText(validation.CPFDigits(input.CPF.Value))
+	}
+	if input.Tags.Set {
+		updates["Tags"] = tagList(input.Tags.Value)
+	}
+	if input.Allergies.Set {
+		updates["Allergies"] = allergies(input.Allergies.Value)
+	}
+	if input.Diet.Set {
+		updates["Diet"] = models.List[string](input.Diet.Value)
+	}
+
+	patched := dbCustomer
+	if input.Email.Set {
+		patched.Email = updates["Email"].(*string)
+	}
+	if input.Phone.Set {
+		patched.Phone = updates["Phone"].(*string)
+	}
+	if input.CPF.Set {
+		patched.CPF = updates["CPF"].(*string)
+	}
 
-	if !errorhandling.CheckOrHttpError(deacoded, w, http.StatusBadRequest, "Invalid input") {
+	err = c.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkUniqueContacts(tx, patched); err != nil {
+			return err
+		}
+		if patched.Email == nil && patched.Phone == nil {
+			return errNoContact
+		}
+
+		updated, err := updateVersioned(tx, &dbCustomer, dbCustomer.Version, updates)
+		if err == nil && !updated {
+			return errVersionConflict
+		}
+		return err
+	})
+	err = contactsError(c.db, patched, err)
+
+	switch err {
+	case nil:
+		break
+
+	case errVersionConflict:
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+		return
+
+	default:
+		status, detail := customerContactsProblem(err)
+		errorhandling.ProblemResponse(w, status, detail)
 		return
 	}
+	w.Header().Set("ETag", httphelpers.ETag(dbCustomer.Version))
 
-	updated := c.db.Model(&dbCustomer).Updates(input)
-	if !errorhandling.CheckOrHttpError(
-		updated.Error, w, http.StatusInternalServerError, "Error updating customer",
-	) {
+	stats, err := customerStats(c.db, dbCustomer.ID)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Error updating customer") {
 		return
 	}
 
-	httphelpers.JsonResponse(
-		w,
+	respond(
+		w, r,
 		http.StatusOK,
-		&schemas.CustomerOutputSchema{
-			ID:    dbCustomer.ID,
-			Fname: dbCustomer.Fname,
-			Lname: dbCustomer.Lname,
-			Email: dbCustomer.Email,
-		},
+		mappers.Customer(dbCustomer, stats[dbCustomer.ID]),
 	)
 }
 
@@ -264,6 +331,9 @@ func (c *CustomerController) UpdateCustomer(w http.ResponseWriter, r *http.Reque
 // If the customer is not found, the function will return a 404 Not Found response.
 //
 // If the customer is successfully deleted, the function will return a 204 No Content response.
+//
+// If the If-Match header does not match the ETag of the customer, the function will return
+// a 412 Precondition Failed response.
 func (c *CustomerController) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	customerId, err := strconv.Atoi(vars["id"])
@@ -283,32 +353,467 @@ func (c *CustomerController) DeleteCustomer(w http.ResponseWriter, r *http.Reque
 
 	switch result.Error {
 	case gorm.ErrRecordNotFound:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusNotFound,
-			&schemas.Message{
-				Code:   http.StatusNotFound,
-				Detail: []string{"Customer not found"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Customer not found")
 		return
 
 	case nil:
 		break
 
 	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			&schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Something went wrong"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbCustomer.Version)) {
 		return
 	}
 
-	dbCustomer.Active = false
-	c.db.Save(&dbCustomer)
-	httphelpers.JsonResponse(w, http.StatusNoContent, nil)
+	deleted, err := updateVersioned(c.db, &dbCustomer, dbCustomer.Version, map[string]any{"Active": false})
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Something went wrong") {
+		return
+	}
+	if !deleted {
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+		return
+	}
+	respond(w, r, http.StatusNoContent, nil)
+}
+
+// DeleteCustomers deactivates the customer of every item of the JSON array of
+// the request body and returns the result of each one, deleted customers
+// getting a 204 No Content status. The items are rejected like on
+// DeleteCustomer, and they are processed as described on runBulk.
+func (c *CustomerController) DeleteCustomers(w http.ResponseWriter, r *http.Request) {
+	var inputCustomers []schemas.CustomerBulkDeleteInputSchema
+	err := json.NewDecoder(r.Body).Decode(&inputCustomers)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid input") {
+		return
+	}
+
+	runBulk(w, r, c.db, len(inputCustomers), http.StatusOK, func(tx *gorm.DB, i int) schemas.BulkItemResult {
+		inputCustomer := inputCustomers[i]
+		if err := c.validator.Struct(inputCustomer); err != nil {
+			return rejectedItem(errorhandling.NewValidationProblem(w, err))
+		}
+
+		var dbCustomer models.Customer
+		switch err := tx.First(&dbCustomer, "id = ? AND active = ?", inputCustomer.ID, true).Error; err {
+		case nil:
+			break
+		case gorm.ErrRecordNotFound:
+			return rejectedItem(errorhandling.NewProblem(w, http.StatusNotFound, "Customer not found"))
+		default:
+			return rejectedItem(errorhandling.NewProblem(w, http.StatusInternalServerError, "Something went wrong"))
+		}
+
+		deleted, err := updateVersioned(tx, &dbCustomer, dbCustomer.Version, map[string]any{"Active": false})
+		switch {
+		case err != nil:
+			return rejectedItem(errorhandling.NewProblem(w, http.StatusInternalServerError, "Something went wrong"))
+		case !deleted:
+			return rejectedItem(errorhandling.NewProblem(
+				w, http.StatusPreconditionFailed, "The resource was modified by another request",
+			))
+		}
+		return schemas.BulkItemResult{ID: dbCustomer.ID, Status: http.StatusNoContent}
+	})
+}
+
+// GetCustomerOrders retrieves the orders of a customer by ID, including the
+// inactive ones, and encodes them as a JSON response with a 200 OK status code.
+// The orders can be filtered by ?status= and ?from= / ?to= like on GET /orders/.
+//
+// If the ID is invalid, the function will return a 400 Bad Request response.
+//
+// If the customer is not found, the function will return a 404 Not Found response.
+func (c *CustomerController) GetCustomerOrders(w http.ResponseWriter, r *http.Request) {
+	dbCustomer, ok := c.customer(w, r)
+	if !ok {
+		return
+	}
+
+	query, ok := filterOrders(w, r, c.db.Where("customer_id = ?", dbCustomer.ID))
+	if !ok {
+		return
+	}
+
+	query, ok = withIncludes(w, r, query, orderIncludes)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseFieldset(w, r, c.db, &models.Order{}, schemas.OrderOutputSchema{})
+	if !ok {
+		return
+	}
+
+	writeList(w, r, fields, fields.selectColumns(query, orderKeyColumns...), orderOutputs)
+}
+
+// CreateCustomerOrder places an order for the customer of the given ID and
+// returns it as a JSON response with a 201 Created status code.
+//
+// If the ID or the request body is invalid, the function will return a 400 Bad Request
+// response, and if the customer is not found, a 404 Not Found response. Orders for
+// inactive customers or unknown cakes are rejected like on POST /orders/.
+func (c *CustomerController) CreateCustomerOrder(w http.ResponseWriter, r *http.Request) {
+	dbCustomer, ok := c.customer(w, r)
+	if !ok {
+		return
+	}
+
+	var inputOrder schemas.CustomerOrderInputSchema
+	err := json.NewDecoder(r.Body).Decode(&inputOrder)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid input") {
+		return
+	}
+
+	if !errorhandling.CheckValidationError(c.validator.Struct(inputOrder), w) {
+		return
+	}
+
+	dbOrder := models.Order{
+		CustomerID: dbCustomer.ID,
+		CakeID:     inputOrder.CakeID,
+		Qtd:        inputOrder.Qtd,
+		Delivered:  inputOrder.Delivered,
+		Notes:      inputOrder.Notes,
+		Pickup:     inputOrder.Pickup,
+		AddressID:  inputOrder.AddressID,
+		OptionIDs:  inputOrder.OptionIDs,
+		DueDate:    inputOrder.DueDate,
+
+		Personalization: jsonDocument(inputOrder.Personalization),
+	}
+
+	if err := createOrder(c.db, &dbOrder); err != nil {
+		createOrderError(w, err)
+		return
+	}
+	respond(w, r, http.StatusCreated, mappers.Order(dbOrder))
+}
+
+// RestoreCustomer reactivates a deleted customer by ID and returns it as a
+// JSON response with a 200 OK status code.
+//
+// If the ID is invalid, the function will return a 400 Bad Request response.
+//
+// If the customer is not found, the function will return a 404 Not Found response, and if
+// it is not deleted, a 409 Conflict response.
+//
+// If the If-Match header does not match the ETag of the customer, the function will return
+// a 412 Precondition Failed response.
+func (c *CustomerController) RestoreCustomer(w http.ResponseWriter, r *http.Request) {
+	dbCustomer, ok := c.deletedCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbCustomer.Version)) {
+		return
+	}
+
+	restored, err := updateVersioned(c.db, &dbCustomer, dbCustomer.Version, map[string]any{"Active": true})
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Something went wrong") {
+		return
+	}
+	if !restored {
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+		return
+	}
+	w.Header().Set("ETag", httphelpers.ETag(dbCustomer.Version))
+
+	stats, err := customerStats(c.db, dbCustomer.ID)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Something went wrong") {
+		return
+	}
+
+	respond(w, r, http.StatusOK, mappers.Customer(dbCustomer, stats[dbCustomer.ID]))
+}
+
+// PurgeCustomer permanently removes a deleted customer by ID from the database
+// and returns a 204 No Content response. It is restricted to admins.
+//
+// If the ID is invalid, the function will return a 400 Bad Request response.
+//
+// If the customer is not found, the function will return a 404 Not Found response, and if
+// it is not deleted, a 409 Conflict response. Customers referenced by orders, including the
+// deleted ones, cannot be purged either, and a 409 Conflict response listing the orders is
+// returned.
+//
+// If the If-Match header does not match the ETag of the customer, the function will return
+// a 412 Precondition Failed response.
+func (c *CustomerController) PurgeCustomer(w http.ResponseWriter, r *http.Request) {
+	dbCustomer, ok := c.deletedCustomer(w, r)
+	if !ok {
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbCustomer.Version)) {
+		return
+	}
+
+	var orderIDs []uint
+	err := c.db.Unscoped().Model(&models.Order{}).Where("customer_id = ?", dbCustomer.ID).Order("id").Pluck("id", &orderIDs).Error
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Something went wrong") {
+		return
+	}
+	if len(orderIDs) > 0 {
+		errorhandling.WriteProblem(w, schemas.Problem{
+			Type:     errorhandling.ProblemType(http.StatusConflict),
+			Title:    http.StatusText(http.StatusConflict),
+			Status:   http.StatusConflict,
+			Detail:   "Customer is referenced by orders",
+			OrderIDs: orderIDs,
+		})
+		return
+	}
+
+	purged := c.db.Where("version = ?", dbCustomer.Version).Delete(&dbCustomer)
+	switch {
+	case errors.Is(purged.Error, gorm.ErrForeignKeyViolated):
+		errorhandling.ProblemResponse(w, http.StatusConflict, "Customer is referenced by orders")
+
+	case purged.Error != nil:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Something went wrong")
+
+	case purged.RowsAffected == 0:
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+
+	default:
+		respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// customer retrieves the customer of the ID on the URL, active or not. If the
+// ID is invalid or the customer does not exist, a problem response is written
+// and false is returned.
+func (c *CustomerController) customer(w http.ResponseWriter, r *http.Request) (models.Customer, bool) {
+	var dbCustomer models.Customer
+
+	vars := mux.Vars(r)
+	customerID, err := strconv.Atoi(vars["id"])
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid customer ID") {
+		return dbCustomer, false
+	}
+
+	result := c.db.First(&dbCustomer, customerID)
+	switch result.Error {
+	case nil:
+		return dbCustomer, true
+
+	case gorm.ErrRecordNotFound:
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Customer not found")
+		return dbCustomer, false
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return dbCustomer, false
+	}
+}
+
+// deletedCustomer retrieves the deleted customer of the ID on the URL. If the
+// ID is invalid, the customer does not exist or it is still active, a problem
+// response is written and false is returned.
+func (c *CustomerController) deletedCustomer(w http.ResponseWriter, r *http.Request) (models.Customer, bool) {
+	dbCustomer, ok := c.customer(w, r)
+	if ok && dbCustomer.Active {
+		errorhandling.ProblemResponse(w, http.StatusConflict, "Customer is not deleted")
+		return dbCustomer, false
+	}
+	return dbCustomer, ok
+}
+
+// createCustomerItem validates and creates a customer of an import, rejecting
+// it like CreateCustomer.
+func createCustomerItem(
+	w http.ResponseWriter, tx *gorm.DB, validator *validator.Validate, inpCustomer schemas.CustomerInputSchema,
+) schemas.BulkItemResult {
+	if err := validator.Struct(inpCustomer); err != nil {
+		return rejectedItem(errorhandling.NewValidationProblem(w, err))
+	}
+
+	dbCustomer := customerModel(inpCustomer)
+	if err := checkUniqueContacts(tx, dbCustomer); err != nil {
+		status, detail := customerContactsProblem(err)
+		return rejectedItem(errorhandling.NewProblem(w, status, detail))
+	}
+
+	if err := tx.Create(&dbCustomer).Error; errors.Is(err, gorm.ErrDuplicatedKey) {
+		status, detail := customerContactsProblem(contactsError(tx, dbCustomer, err))
+		return rejectedItem(errorhandling.NewProblem(w, status, detail))
+	} else if err != nil {
+		return rejectedItem(errorhandling.NewProblem(w, http.StatusInternalServerError, "Error creating customer"))
+	}
+	return schemas.BulkItemResult{
+		ID:     dbCustomer.ID,
+		Status: http.StatusCreated,
+		Data:   mappers.Customer(dbCustomer, &schemas.CustomerStats{}),
+	}
+}
+
+// customerModel builds the customer of the creation input, with the phone in
+// the E.164 format, the CPF digits only, nil for the contacts left blank and
+// the tags in lower case.
+func customerModel(inpCustomer schemas.CustomerInputSchema) models.Customer {
+	phone, _ := validation.NormalizePhone(inpCustomer.Phone)
+	return models.Customer{
+		Fname:     inpCustomer.Fname,
+		Lname:     inpCustomer.Lname,
+		Email:     optionalText(inpCustomer.Email),
+		Phone:     optionalText(phone),
+		CPF:       optionalText(validation.CPFDigits(inpCustomer.CPF)),
+		Notes:     inpCustomer.Notes,
+		Tags:      tagList(inpCustomer.Tags),
+		Allergies: allergies(inpCustomer.Allergies),
+		Diet:      inpCustomer.Diet,
+	}
+}
+
+// tagList trims the tags of a customer or cake and converts them to lower
+// case, leaving out the repeated ones.
+func tagList(tags []string) models.List[string] {
+	normalized := make(models.List[string], 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// allergies converts the allergies of the input schemas to the ones stored
+// on the customers.
+func allergies(inputAllergies []schemas.AllergySchema) models.List[models.Allergy] {
+	stored := make(models.List[models.Allergy], 0, len(inputAllergies))
+	for _, allergy := range inputAllergies {
+		stored = append(stored, models.Allergy{Allergen: allergy.Allergen, Severe: allergy.Severe})
+	}
+	return stored
+}
+
+// optionalText returns a pointer to the text, or nil when it is blank, for the
+// optional unique columns holding NULL rather than empty strings.
+func optionalText(text string) *string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	return &text
+}
+
+// checkUniqueContacts returns errEmailExists or errCPFExists when another
+// customer, active or not, has the email or the CPF of the given one.
+func checkUniqueContacts(tx *gorm.DB, dbCustomer models.Customer) error {
+	if dbCustomer.Email != nil &&
+		tx.First(&models.Customer{}, "email = ? AND id <> ?", *dbCustomer.Email, dbCustomer.ID).RowsAffected > 0 {
+		return errEmailExists
+	}
+	if dbCustomer.CPF != nil &&
+		tx.First(&models.Customer{}, "cpf = ? AND id <> ?", *dbCustomer.CPF, dbCustomer.ID).RowsAffected > 0 {
+		return errCPFExists
+	}
+	return nil
+}
+
+// contactsError returns errEmailExists or errCPFExists when a write failed
+// on the unique email or CPF, which another request may have taken since
+// checkUniqueContacts ran. Other errors are returned unchanged.
+func contactsError(tx *gorm.DB, dbCustomer models.Customer, err error) error {
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return err
+	}
+	if taken := checkUniqueContacts(tx, dbCustomer); taken != nil {
+		return taken
+	}
+	return err
+}
+
+// customerContactsProblem returns the status code and detail message of the
+// problem reported for the errors of the customer contacts.
+func customerContactsProblem(err error) (int, string) {
+	switch err {
+	case errEmailExists:
+		return http.StatusConflict, "Email already exists"
+
+	case errCPFExists:
+		return http.StatusConflict, "CPF already exists"
+
+	case errNoContact:
+		return http.StatusBadRequest, "The customer needs an email or a phone"
+
+	case gorm.ErrDuplicatedKey:
+		return http.StatusConflict, "Email or CPF already exists"
+
+	default:
+		return http.StatusInternalServerError, "Error updating customer"
+	}
+}
+
+// customerOutputs converts the customers to the output schema with their
+// stats.
+func customerOutputs(db *gorm.DB, dbCustomers []models.Customer) ([]schemas.CustomerOutputSchema, error) {
+	customerIDs := make([]uint, len(dbCustomers))
+	for i, dbCustomer := range dbCustomers {
+		customerIDs[i] = dbCustomer.ID
+	}
+
+	stats, err := customerStats(db, customerIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	outputCustomers := make([]schemas.CustomerOutputSchema, len(dbCustomers))
+	for i, dbCustomer := range dbCustomers {
+		outputCustomers[i] = mappers.Customer(dbCustomer, stats[dbCustomer.ID])
+	}
+	return outputCustomers, nil
+}
+
+// customerStats computes the lifetime stats of the given customers from the
+// orders that were not deleted, keyed by customer ID.
+func customerStats(db *gorm.DB, customerIDs ...uint) (map[uint]*schemas.CustomerStats, error) {
+	var totals []struct {
+		CustomerID uint
+		OrderCount int64
+		TotalSpent uint64
+	}
+	err := db.Model(&models.Order{}).
+		Select("orders.customer_id, COUNT(*) AS order_count, SUM(orders.total) AS total_spent").
+		Where("orders.customer_id IN ?", customerIDs).
+		Group("orders.customer_id").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// the dates are selected from the column itself rather than with MAX(),
+	// whose result the driver would not convert to a time
+	var lastOrders []struct {
+		CustomerID uint
+		CreatedAt  time.Time
+	}
+	latest := db.Model(&models.Order{}).Select("MAX(orders.created_at)").Where("orders.customer_id = last.customer_id")
+	err = db.Table("orders AS last").
+		Select("last.customer_id, last.created_at").
+		Where("last.deleted_at IS NULL AND last.customer_id IN ?", customerIDs).
+		Where("last.created_at = (?)", latest).
+		Scan(&lastOrders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[uint]*schemas.CustomerStats, len(customerIDs))
+	for _, customerID := range customerIDs {
+		stats[customerID] = &schemas.CustomerStats{}
+	}
+	for _, total := range totals {
+		stats[total.CustomerID].OrderCount = total.OrderCount
+		stats[total.CustomerID].TotalSpent = total.TotalSpent
+	}
+	for _, lastOrder := range lastOrders {
+		stats[lastOrder.CustomerID].LastOrderAt = &lastOrder.CreatedAt
+	}
+	return stats, nil
 }
@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/config/validation"
+	"github.com/LeandroDeJesus-S/confectionery/internal/mappers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+	"github.com/LeandroDeJesus-S/confectionery/internal/search"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/patch"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// errInvalidDeliveryZone is returned for zones with an incomplete or reversed
+// CEP range, or with neither a CEP range nor a neighborhood
+var errInvalidDeliveryZone = errors.New("invalid delivery zone")
+
+// DeliveryZoneController manages the areas covered by the deliveries and
+// their fees.
+type DeliveryZoneController struct {
+	db        *gorm.DB
+	validator *validator.Validate
+}
+
+// NewDeliveryZoneController initializes the DeliveryZoneController structure
+func NewDeliveryZoneController(db *gorm.DB, validator *validator.Validate) *DeliveryZoneController {
+	return &DeliveryZoneController{db: db, validator: validator}
+}
+
+// GetDeliveryZones retrieves every delivery zone from the database and
+// encodes them as a JSON response with a 200 OK status code.
+func (c *DeliveryZoneController) GetDeliveryZones(w http.ResponseWriter, r *http.Request) {
+	var dbZones []models.DeliveryZone
+	err := c.db.Order("id").Find(&dbZones).Error
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+	respond(w, r, http.StatusOK, mappers.DeliveryZones(dbZones))
+}
+
+// CreateDeliveryZone creates a new delivery zone and returns it as a JSON
+// response with a 201 Created status code.
+//
+// If the request body is invalid, or the zone has an incomplete or reversed CEP range, the
+// function will return a 400 Bad Request response, and if the name already exists, a 409
+// Conflict response.
+func (c *DeliveryZoneController) CreateDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	var inputZone schemas.DeliveryZoneInputSchema
+	err := json.NewDecoder(r.Body).Decode(&inputZone)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid input") {
+		return
+	}
+
+	if !errorhandling.CheckValidationError(c.validator.Struct(inputZone), w) {
+		return
+	}
+
+	dbZone := models.DeliveryZone{
+		Name:         inputZone.Name,
+		CEPStart:     validation.CEPDigits(inputZone.CEPStart),
+		CEPEnd:       validation.CEPDigits(inputZone.CEPEnd),
+		Neighborhood: inputZone.Neighborhood,
+		City:         inputZone.City,
+		Fee:          inputZone.Fee,
+	}
+	if checkDeliveryZone(dbZone) != nil {
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid delivery zone")
+		return
+	}
+
+	if c.db.First(&models.DeliveryZone{}, "name = ?", dbZone.Name).RowsAffected > 0 {
+		errorhandling.ProblemResponse(w, http.StatusConflict, "Delivery zone already exists")
+		return
+	}
+
+	err = c.db.Create(&dbZone).Error
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+
+	w.Header().Set("ETag", httphelpers.ETag(dbZone.Version))
+	respond(w, r, http.StatusCreated, mappers.DeliveryZone(dbZone))
+}
+
+// UpdateDeliveryZone updates a delivery zone by ID with a JSON Merge Patch or
+// a JSON Patch and returns it as a JSON response with a 200 OK status code.
+// The orders already placed keep the fee they were placed with.
+//
+// If the ID or the request body is invalid, or the patched zone would have an incomplete or
+// reversed CEP range, the function will return a 400 Bad Request response. If the zone is not
+// found, it returns a 404 Not Found response, and if the name already exists, a 409 Conflict
+// response.
+//
+// If the If-Match header does not match the ETag of the zone, or the zone is changed by
+// another request meanwhile, the function will return a 412 Precondition Failed response.
+func (c *DeliveryZoneController) UpdateDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	dbZone, ok := c.deliveryZone(w, r)
+	if !ok {
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbZone.Version)) {
+		return
+	}
+
+	var inputZone schemas.DeliveryZonePatchInputSchema
+	if !errorhandling.CheckPatchError(patch.Decode(r, dbZone, &inputZone), w) {
+		return
+	}
+
+	if !errorhandling.CheckValidationError(c.validator.Struct(inputZone), w) {
+		return
+	}
+
+	if inputZone.Name.Set {
+		duplicated := c.db.First(&models.DeliveryZone{}, "name = ? AND id <> ?", inputZone.Name.Value, dbZone.ID)
+		if duplicated.RowsAffected > 0 {
+			errorhandling.ProblemResponse(w, http.StatusConflict, "Delivery zone already exists")
+			return
+		}
+	}
+
+	updates := patch.Updates(inputZone)
+	if inputZone.CEPStart.Set {
+		updates["CEPStart"] = validation.CEPDigits(inputZone.CEPStart.Value)
+	}
+	if inputZone.CEPEnd.Set {
+		updates["CEPEnd"] = validation.CEPDigits(inputZone.CEPEnd.Value)
+	}
+
+	// the zone is checked as patched, rolling the update back when invalid
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		updated, err := updateVersioned(tx, &dbZone, dbZone.Version, updates)
+		switch {
+		case err != nil:
+			return err
+		case !updated:
+			return errVersionConflict
+		}
+		return checkDeliveryZone(dbZone)
+	})
+
+	switch err {
+	case nil:
+		w.Header().Set("ETag", httphelpers.ETag(dbZone.Version))
+		respond(w, r, http.StatusOK, mappers.DeliveryZone(dbZone))
+
+	case errInvalidDeliveryZone:
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid delivery zone")
+
+	case errVersionConflict:
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// DeleteDeliveryZone deletes a delivery zone by ID and returns a 204 No
+// Content response. The orders already placed keep their delivery fee.
+//
+// If the ID is invalid, the function will return a 400 Bad Request response, and if the
+// zone is not found, a 404 Not Found response.
+//
+// If the If-Match header does not match the ETag of the zone, the function will return a
+// 412 Precondition Failed response.
+func (c *DeliveryZoneController) DeleteDeliveryZone(w http.ResponseWriter, r *http.Request) {
+	dbZone, ok := c.deliveryZone(w, r)
+	if !ok {
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbZone.Version)) {
+		return
+	}
+
+	deleted := c.db.Where("version = ?", dbZone.Version).Delete(&dbZone)
+	switch {
+	case deleted.Error != nil:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+
+	case deleted.RowsAffected == 0:
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+
+	default:
+		respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// deliveryZone retrieves the delivery zone of the ID on the URL. If the ID is
+// invalid or the zone does not exist, a problem response is written and false
+// is returned.
+func (c *DeliveryZoneController) deliveryZone(w http.ResponseWriter, r *http.Request) (models.DeliveryZone, bool) {
+	var dbZone models.DeliveryZone
+
+	vars := mux.Vars(r)
+	zoneID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid delivery zone id") {
+		return dbZone, false
+	}
+
+	switch err := c.db.First(&dbZone, zoneID).Error; err {
+	case nil:
+		return dbZone, true
+
+	case gorm.ErrRecordNotFound:
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Delivery zone not found")
+		return dbZone, false
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		return dbZone, false
+	}
+}
+
+// checkDeliveryZone checks that the zone has a complete CEP range, whose start
+// is not after its end, or a neighborhood.
+func checkDeliveryZone(zone models.DeliveryZone) error {
+	switch {
+	case (zone.CEPStart == "") != (zone.CEPEnd == ""):
+		return errInvalidDeliveryZone
+	case zone.CEPStart > zone.CEPEnd:
+		return errInvalidDeliveryZone
+	case zone.CEPStart == "" && zone.Neighborhood == "":
+		return errInvalidDeliveryZone
+	}
+	return nil
+}
+
+// deliveryZoneOf returns the delivery zone covering the address. Zones whose
+// CEP range holds the CEP of the address take precedence over the ones
+// matching its neighborhood, which are compared ignoring case and accents.
+// Among several matching zones the oldest one is chosen, and when no zone
+// covers the address errOutsideDeliveryZones is returned.
+func deliveryZoneOf(tx *gorm.DB, address models.Address) (models.DeliveryZone, error) {
+	var zone models.DeliveryZone
+	err := tx.Where("cep_start <> '' AND cep_start <= ? AND cep_end >= ?", address.CEP, address.CEP).
+		Order("id").First(&zone).Error
+	if err != gorm.ErrRecordNotFound {
+		return zone, err
+	}
+
+	var zones []models.DeliveryZone
+	if err := tx.Where("neighborhood <> ''").Order("id").Find(&zones).Error; err != nil {
+		return zone, err
+	}
+	for _, candidate := range zones {
+		if search.Fold(candidate.Neighborhood) != search.Fold(address.Neighborhood) {
+			continue
+		}
+		if candidate.City == "" || search.Fold(candidate.City) == search.Fold(address.City) {
+			return candidate, nil
+		}
+	}
+	return zone, errOutsideDeliveryZones
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/spreadsheet"
+	"gorm.io/gorm"
+)
+
+// fieldset is the sparse fieldset requested with ?fields=. It holds the JSON
+// names of the fields to return and the columns selected for them. A nil
+// fieldset returns every field.
+type fieldset struct {
+	names   []string
+	columns []string
+}
+
+// parseFieldset reads ?fields= against the fields of the output schema,
+// mapping them to the columns of the model fields of the same Go name.
+// Fields of the output schema computed by the API, such as the customer
+// stats, are accepted without a column, while the embedded resources are
+// requested with ?include= instead. If an unknown field is requested, a
+// 400 Bad Request problem listing the accepted ones is written and false is
+// returned.
+func parseFieldset(w http.ResponseWriter, r *http.Request, db *gorm.DB, model any, output any) (*fieldset, bool) {
+	requested := httphelpers.QueryList(r, "fields")
+	if len(requested) == 0 {
+		return nil, true
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		return nil, false
+	}
+
+	columns := make(map[string]string)
+	outputFields(reflect.TypeOf(output), func(name, goName string) {
+		columns[name] = ""
+		if field := stmt.Schema.LookUpField(goName); field != nil && field.DBName != "" {
+			columns[name] = field.DBName
+		}
+	})
+
+	fields := &fieldset{}
+	var unknown []string
+	for _, name := range requested {
+		column, ok := columns[name]
+		switch {
+		case !ok:
+			unknown = append(unknown, name)
+		case !slices.Contains(fields.names, name):
+			fields.names = append(fields.names, name)
+			if column != "" {
+				fields.columns = append(fields.columns, column)
+			}
+		}
+	}
+
+	if len(unknown) > 0 {
+		allowed := make([]string, 0, len(columns))
+		for name := range columns {
+			allowed = append(allowed, name)
+		}
+		slices.Sort(allowed)
+
+		errorhandling.WriteProblem(w, schemas.Problem{
+			Type:          errorhandling.ProblemType(http.StatusBadRequest),
+			Title:         http.StatusText(http.StatusBadRequest),
+			Status:        http.StatusBadRequest,
+			Detail:        "Unknown fields requested",
+			InvalidFields: unknown,
+			AllowedFields: allowed,
+		})
+		return nil, false
+	}
+	return fields, true
+}
+
+// selectColumns restricts the query to the requested columns, plus the
+// required ones, such as the keys needed to preload the associations.
+func (f *fieldset) selectColumns(query *gorm.DB, required ...string) *gorm.DB {
+	if f == nil {
+		return query
+	}
+
+	columns := slices.Clone(f.columns)
+	for _, column := range required {
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return query.Select(columns)
+}
+
+// write encodes the output, restricted to the fieldset, with the given status
+// code as described on respond.
+func (f *fieldset) write(w http.ResponseWriter, r *http.Request, status int, output any) {
+	respond(w, r, status, f.pick(r, output))
+}
+
+// writeVersion encodes the output of a resource at the given version,
+// restricted to the fieldset, as described on respondVersion.
+func (f *fieldset) writeVersion(w http.ResponseWriter, r *http.Request, version uint, output any) {
+	respondVersion(w, r, version, f.pick(r, output))
+}
+
+// pick returns the output, restricted to the requested fields and the
+// embedded resources. The fields are picked from the output schema, so they
+// keep its order and the types of their values.
+func (f *fieldset) pick(r *http.Request, output any) any {
+	if f == nil {
+		return output
+	}
+	return httphelpers.SelectFields(output, f.keep(r), false)
+}
+
+// header returns the empty output of a list naming its CSV columns, holding
+// every requested field, even the ones omitted from the outputs when empty.
+func (f *fieldset) header(r *http.Request, output any) any {
+	if f == nil {
+		return output
+	}
+	return httphelpers.SelectFields(output, f.keep(r), true)
+}
+
+// has reports whether the field is returned, as every field is without a
+// fieldset.
+func (f *fieldset) has(name string) bool {
+	return f == nil || slices.Contains(f.names, name)
+}
+
+// keep lists the JSON names of the fields kept on the outputs: the requested
+// fields and the embedded resources.
+func (f *fieldset) keep(r *http.Request) []string {
+	return append(slices.Clone(f.names), httphelpers.QueryList(r, "include")...)
+}
+
+// outputFields calls fn with the JSON and Go names of every field of the
+// output schema, flattening the embedded structs. Fields holding other
+// resources omitted when empty, the ones embedded with ?include=, are
+// skipped.
+func outputFields(t reflect.Type, fn func(name, goName string)) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && name == "" {
+			outputFields(fieldType, fn)
+			continue
+		}
+		if spreadsheet.IsResource(fieldType) && (strings.Contains(options, "omitempty") || strings.Contains(options, "omitzero")) {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fn(name, field.Name)
+	}
+}
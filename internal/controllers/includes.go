@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"gorm.io/gorm"
+)
+
+// orderIncludes maps the values accepted by ?include= on the order routes to
+// the associations preloaded for them
+var orderIncludes = map[string]string{
+	"customer": "Customer",
+	"cake":     "Cake.Images",
+	"address":  "Address",
+}
+
+// customerIncludes maps the values accepted by ?include= on the customer
+// routes to the associations preloaded for them
+var customerIncludes = map[string]string{
+	"orders":    "Orders",
+	"addresses": "Addresses",
+}
+
+// cakeIncludes maps the values accepted by ?include= on the cake routes to
+// the associations preloaded for them. The cake list also takes "archived".
+// The images are always embedded, and "images" is kept for the clients
+// already requesting them.
+var cakeIncludes = map[string]string{
+	"options": "Options",
+	"images":  "Images",
+}
+
+// withIncludes preloads the associations requested on ?include=, so the
+// related resources are loaded with one query per association instead of
+// one per record. If an unknown value is requested, a 400 Bad Request
+// problem is written and false is returned.
+func withIncludes(w http.ResponseWriter, r *http.Request, query *gorm.DB, includes map[string]string) (*gorm.DB, bool) {
+	for _, include := range httphelpers.QueryList(r, "include") {
+		association, ok := includes[include]
+		if !ok {
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid include parameter")
+			return nil, false
+		}
+		query = query.Preload(association, func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("id")
+		})
+	}
+	return query, true
+}
+
+// preloadImages preloads the images of the cakes, which are embedded on every
+// cake returned.
+func preloadImages(query *gorm.DB) *gorm.DB {
+	return query.Preload("Images", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("id")
+	})
+}
@@ -2,13 +2,20 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/LeandroDeJesus-S/confectionery/internal/mappers"
 	"github.com/LeandroDeJesus-S/confectionery/internal/models"
 	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
 	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
 	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/jsonschema"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/patch"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
@@ -24,11 +31,53 @@ func NewOrdersController(db *gorm.DB, validator *validator.Validate) *OrdersCont
 }
 
 // GetOrders retrieves all the orders from the database and encodes them
-// as a JSON response with an HTTP status code 200 OK.
+// as a JSON response with an HTTP status code 200 OK. Deleted orders are
+// listed too with ?includeDeleted=true, the orders can be filtered as
+// described on filterOrders and ?fields= selects the fields returned.
 func (c *OrdersController) GetOrders(w http.ResponseWriter, r *http.Request) {
-	var dbOrders []models.Order
-	c.db.Find(&dbOrders)
-	httphelpers.JsonResponse(w, http.StatusOK, dbOrders)
+	includeDeleted, err := httphelpers.QueryBool(r, "includeDeleted")
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid includeDeleted parameter") {
+		return
+	}
+
+	query := c.db
+	if includeDeleted {
+		query = c.db.Unscoped()
+	}
+
+	query, ok := filterOrders(w, r, query)
+	if !ok {
+		return
+	}
+
+	query, ok = withIncludes(w, r, query, orderIncludes)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseFieldset(w, r, c.db, &models.Order{}, schemas.OrderOutputSchema{})
+	if !ok {
+		return
+	}
+
+	writeList(w, r, fields, fields.selectColumns(query, orderKeyColumns...), orderOutputs)
+}
+
+// GetOrdersTrash retrieves the deleted orders from the database and encodes
+// them as a JSON response with an HTTP status code 200 OK.
+func (c *OrdersController) GetOrdersTrash(w http.ResponseWriter, r *http.Request) {
+	query, ok := withIncludes(w, r, c.db.Unscoped(), orderIncludes)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseFieldset(w, r, c.db, &models.Order{}, schemas.OrderOutputSchema{})
+	if !ok {
+		return
+	}
+
+	query = fields.selectColumns(query, orderKeyColumns...).Where("deleted_at IS NOT NULL")
+	writeList(w, r, fields, query, orderOutputs)
 }
 
 // GetOrder retrieves an order by ID from the database and encodes it
@@ -38,9 +87,12 @@ func (c *OrdersController) GetOrders(w http.ResponseWriter, r *http.Request) {
 //
 // If the order is not found, the function will return a 404 Not Found response.
 //
-// If the order is successfully retrieved, the function will return the order details as a
-// JSON response with a 200 OK status code. If there are any server errors, it
+// If the order is successfully retrieved, the function will return the order details, along
+// with the allergens of its cake, as a JSON response with a 200 OK status code. If there are any server errors, it
 // returns a 500 Internal Server Error response.
+//
+// The response carries the ETag of its representation, and a 304 Not Modified response is returned
+// when it matches the If-None-Match header. The fields returned can be selected with ?fields=.
 func (c *OrdersController) GetOrder(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseUint(vars["id"], 10, 32)
@@ -49,39 +101,41 @@ func (c *OrdersController) GetOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query, ok := withIncludes(w, r, c.db, orderIncludes)
+	if !ok {
+		return
+	}
+
+	fields, ok := parseFieldset(w, r, c.db, &models.Order{}, schemas.OrderOutputSchema{})
+	if !ok {
+		return
+	}
+
 	var dbOrder models.Order
-	result := c.db.First(&dbOrder, id)
+	result := fields.selectColumns(query, orderKeyColumns...).First(&dbOrder, id)
 
 	switch result.Error {
 	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Internal server error"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
 
 	case gorm.ErrRecordNotFound:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusNotFound,
-			schemas.Message{
-				Code:   http.StatusNotFound,
-				Detail: []string{"Order not found"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Order not found")
 
 	case nil:
-		httphelpers.JsonResponse(w, http.StatusOK, dbOrder)
+		var dbCake models.Cake
+		err := c.db.Select("id", "allergens").First(&dbCake, dbOrder.CakeID).Error
+		if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+			return
+		}
+		fields.writeVersion(w, r, dbOrder.Version, mappers.OrderDetails(dbOrder, dbCake))
 	}
 }
 
 // CreateOrder creates a new order in the database and returns it as a JSON response.
 //
-// If the request body is invalid or the email already exists, the function will return an appropriate
-// HTTP status code and a JSON response with an error message.
+// The customer and cake are checked and the order is inserted in a single transaction. If the
+// request body is invalid, the customer or cake does not exist, or the customer is inactive, the
+// function will return an appropriate HTTP status code and a JSON response with an error message.
 //
 // If the order is successfully created, the function will return the created order as a JSON
 // response with the HTTP status code 201 Created.
@@ -93,15 +147,7 @@ func (c *OrdersController) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	isValidStruct := c.validator.Struct(inputOrder)
-	if !errorhandling.CheckOrHttpError(isValidStruct, w, http.StatusBadRequest, "Invalid input") {
-		return
-	}
-
-	customerExists := c.db.First(&models.Customer{}, "id = ?", inputOrder.CustomerID).RowsAffected > 0
-	cakeExists := c.db.First(&models.Cake{}, "id = ?", inputOrder.CakeID).RowsAffected > 0
-	if !customerExists || !cakeExists {
-		m := schemas.Message{Code: http.StatusBadRequest, Detail: []string{"Customer or Cake not found"}}
-		httphelpers.JsonResponse(w, http.StatusBadRequest, m)
+	if !errorhandling.CheckValidationError(isValidStruct, w) {
 		return
 	}
 
@@ -110,39 +156,35 @@ func (c *OrdersController) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		CakeID:     inputOrder.CakeID,
 		Qtd:        inputOrder.Qtd,
 		Delivered:  inputOrder.Delivered,
-	}
+		Notes:      inputOrder.Notes,
+		Pickup:     inputOrder.Pickup,
+		AddressID:  inputOrder.AddressID,
+		OptionIDs:  inputOrder.OptionIDs,
+		DueDate:    inputOrder.DueDate,
 
-	result := c.db.Create(&dbOrder)
-
-	switch result.Error {
-	case nil:
-		httphelpers.JsonResponse(w, http.StatusCreated, dbOrder)
-
-	case gorm.ErrDuplicatedKey:
-		m := schemas.Message{Code: http.StatusBadRequest, Detail: []string{"Order already exists"}}
-		httphelpers.JsonResponse(w, http.StatusBadRequest, m)
+		Personalization: jsonDocument(inputOrder.Personalization),
+	}
 
-	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Internal server error"},
-			},
-		)
+	if err := createOrder(c.db, &dbOrder); err != nil {
+		createOrderError(w, err)
+		return
 	}
+	respond(w, r, http.StatusCreated, mappers.Order(dbOrder))
 }
 
 // UpdateOrder updates an order by ID in the database.
 // It parses the order ID from the URL, verifies its validity, and retrieves
 // the existing order record. If the order is not found, it returns a 404
-// Not Found response. The function then decodes the request body for partial
-// updates, validates the input, and checks for unique cake and customer
-// constraints. If any validation fails, it sends a 400 Bad Request response.
+// Not Found response. The function then decodes the request body, a JSON
+// Merge Patch or a JSON Patch, validates the input, and saves the changes in
+// a single transaction as described on patchOrder. If any validation fails,
+// it sends a 400 Bad Request response.
 // Upon successful update, it saves the changes and returns the updated order
 // details as a JSON response with a 200 OK status code. If there are any
-// server errors, it returns a 500 Internal Server Error response.
+// server errors, it returns a 500 Internal Server Error response. If the
+// If-Match header does not match the ETag of the order, or the order is
+// changed by another request meanwhile, it returns a 412 Precondition Failed
+// response.
 func (c *OrdersController) UpdateOrder(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	OrderId, err := strconv.ParseUint(vars["id"], 10, 32)
@@ -152,52 +194,100 @@ func (c *OrdersController) UpdateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var inputOrder schemas.OrderPatchInputSchema
-	hasDecoded := json.NewDecoder(r.Body).Decode(&inputOrder)
-	if !errorhandling.CheckOrHttpError(hasDecoded, w, http.StatusBadRequest, "Invalid input") {
+	var dbOrder models.Order
+	result := c.db.First(&dbOrder, OrderId)
+
+	switch result.Error {
+	case nil:
+		break
+
+	case gorm.ErrRecordNotFound:
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Order not found")
+		return
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
-	var dbOrder models.Order
-	result := c.db.First(&dbOrder, OrderId)
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbOrder.Version)) {
+		return
+	}
 
-	isUpdatingCustomerOrCake := inputOrder.CakeID != 0 || inputOrder.CustomerID != 0
-	if isUpdatingCustomerOrCake {
-		cakeExists := c.db.First(&models.Cake{}, "id = ?", inputOrder.CakeID).RowsAffected > 0
-		customerExists := c.db.First(&models.Customer{}, "id = ?", inputOrder.CustomerID).RowsAffected > 0
+	var inputOrder schemas.OrderPatchInputSchema
+	if !errorhandling.CheckPatchError(patch.Decode(r, dbOrder, &inputOrder), w) {
+		return
+	}
 
-		if !cakeExists || !customerExists {
-			m := schemas.Message{Code: http.StatusBadRequest, Detail: []string{"Customer or Cake not found"}}
-			httphelpers.JsonResponse(w, http.StatusBadRequest, m)
-			return
-		}
+	if !errorhandling.CheckValidationError(c.validator.Struct(inputOrder), w) {
+		return
 	}
 
-	switch result.Error {
+	err = c.db.Transaction(func(tx *gorm.DB) error {
+		return patchOrder(tx, &dbOrder, inputOrder)
+	})
+
+	switch err {
 	case nil:
-		c.db.Model(&dbOrder).Updates(inputOrder)
-		httphelpers.JsonResponse(w, http.StatusOK, dbOrder)
+		break
 
-	case gorm.ErrRecordNotFound:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusNotFound,
-			schemas.Message{
-				Code:   http.StatusNotFound,
-				Detail: []string{"Order not found"},
-			},
-		)
+	case errVersionConflict:
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+		return
 
 	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Internal server error"},
-			},
-		)
+		checkOrderReferencesError(w, err)
+		return
+	}
+	w.Header().Set("ETag", httphelpers.ETag(dbOrder.Version))
+	respond(w, r, http.StatusOK, mappers.Order(dbOrder))
+}
+
+// UpdateOrders applies the JSON Merge Patch of every item of the JSON array of
+// the request body to the order of its ID, such as marking many orders as
+// delivered, and returns the result of each one, updated orders getting a
+// 200 OK status and the updated order as data. The items are validated and
+// rejected like on UpdateOrder, and they are processed as described on runBulk.
+func (c *OrdersController) UpdateOrders(w http.ResponseWriter, r *http.Request) {
+	var inputOrders []schemas.OrderBulkPatchInputSchema
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if !errorhandling.CheckOrHttpError(decoder.Decode(&inputOrders), w, http.StatusBadRequest, "Invalid input") {
+		return
 	}
+
+	runBulk(w, r, c.db, len(inputOrders), http.StatusOK, func(tx *gorm.DB, i int) schemas.BulkItemResult {
+		inputOrder := inputOrders[i]
+		if err := c.validator.Struct(inputOrder); err != nil {
+			return rejectedItem(errorhandling.NewValidationProblem(w, err))
+		}
+
+		var dbOrder models.Order
+		switch err := tx.First(&dbOrder, inputOrder.ID).Error; err {
+		case nil:
+			break
+		case gorm.ErrRecordNotFound:
+			return rejectedItem(errorhandling.NewProblem(w, http.StatusNotFound, "Order not found"))
+		default:
+			return rejectedItem(errorhandling.NewProblem(w, http.StatusInternalServerError, "Internal server error"))
+		}
+
+		switch err := patchOrder(tx, &dbOrder, inputOrder.Patch); err {
+		case nil:
+			break
+		case errVersionConflict:
+			return rejectedItem(errorhandling.NewProblem(
+				w, http.StatusPreconditionFailed, "The resource was modified by another request",
+			))
+		default:
+			if problem := errorhandling.NewSchemaProblem(w, err, "personalization"); problem != nil {
+				return rejectedItem(problem)
+			}
+			status, detail := orderReferencesProblem(err)
+			return rejectedItem(errorhandling.NewProblem(w, status, detail))
+		}
+		return schemas.BulkItemResult{ID: dbOrder.ID, Status: http.StatusOK, Data: mappers.Order(dbOrder)}
+	})
 }
 
 // DeleteOrder deletes an order by ID and returns a 204 No Content response.
@@ -207,6 +297,9 @@ func (c *OrdersController) UpdateOrder(w http.ResponseWriter, r *http.Request) {
 // If the order is not found, the function will return a 404 Not Found response.
 //
 // If the order is successfully deleted, the function will return a 204 No Content response.
+//
+// If the If-Match header does not match the ETag of the order, the function will return
+// a 412 Precondition Failed response.
 func (c *OrdersController) DeleteOrder(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	OrderId, err := strconv.ParseUint(vars["id"], 10, 32)
@@ -221,27 +314,683 @@ func (c *OrdersController) DeleteOrder(w http.ResponseWriter, r *http.Request) {
 
 	switch result.Error {
 	case nil:
-		c.db.Delete(&dbOrder)
-		httphelpers.JsonResponse(w, http.StatusNoContent, nil)
+		if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbOrder.Version)) {
+			return
+		}
+
+		deleted := c.db.Where("version = ?", dbOrder.Version).Delete(&dbOrder)
+		switch {
+		case deleted.Error != nil:
+			errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		case deleted.RowsAffected == 0:
+			errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+		default:
+			respond(w, r, http.StatusNoContent, nil)
+		}
 
 	case gorm.ErrRecordNotFound:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusNotFound,
-			schemas.Message{
-				Code:   http.StatusNotFound,
-				Detail: []string{"Order not found"},
-			},
-		)
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Order not found")
+
+	default:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// RestoreOrder brings a deleted order back by ID and returns it as a JSON
+// response with a 200 OK status code.
+//
+// If the ID is invalid, the function will return a 400 Bad Request response.
+//
+// If the order is not found, the function will return a 404 Not Found response, and if
+// it is not deleted, a 409 Conflict response.
+//
+// If the If-Match header does not match the ETag of the order, the function will return
+// a 412 Precondition Failed response.
+func (c *OrdersController) RestoreOrder(w http.ResponseWriter, r *http.Request) {
+	dbOrder, ok := c.deletedOrder(w, r)
+	if !ok {
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbOrder.Version)) {
+		return
+	}
+
+	restored, err := updateVersioned(c.db.Unscoped(), &dbOrder, dbOrder.Version, map[string]any{"DeletedAt": nil})
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+	if !restored {
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+		return
+	}
+	dbOrder.DeletedAt = gorm.DeletedAt{}
+
+	w.Header().Set("ETag", httphelpers.ETag(dbOrder.Version))
+	respond(w, r, http.StatusOK, mappers.Order(dbOrder))
+}
+
+// PurgeOrder permanently removes a deleted order by ID from the database and
+// returns a 204 No Content response. It is restricted to admins.
+//
+// If the ID is invalid, the function will return a 400 Bad Request response.
+//
+// If the order is not found, the function will return a 404 Not Found response, and if
+// it is not deleted, a 409 Conflict response.
+//
+// If the If-Match header does not match the ETag of the order, the function will return
+// a 412 Precondition Failed response.
+func (c *OrdersController) PurgeOrder(w http.ResponseWriter, r *http.Request) {
+	dbOrder, ok := c.deletedOrder(w, r)
+	if !ok {
+		return
+	}
+
+	if !errorhandling.CheckIfMatch(w, r, httphelpers.ETag(dbOrder.Version)) {
+		return
+	}
+
+	purged := c.db.Unscoped().Where("version = ?", dbOrder.Version).Delete(&dbOrder)
+	switch {
+	case purged.Error != nil:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+
+	case purged.RowsAffected == 0:
+		errorhandling.ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+
+	default:
+		respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// deletedOrder retrieves the deleted order of the ID on the URL. If the ID is
+// invalid, the order does not exist or it is not deleted, a problem response
+// is written and false is returned.
+func (c *OrdersController) deletedOrder(w http.ResponseWriter, r *http.Request) (models.Order, bool) {
+	var dbOrder models.Order
+
+	vars := mux.Vars(r)
+	orderID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid Order id") {
+		return dbOrder, false
+	}
+
+	result := c.db.Unscoped().First(&dbOrder, orderID)
+	switch {
+	case result.Error == gorm.ErrRecordNotFound:
+		errorhandling.ProblemResponse(w, http.StatusNotFound, "Order not found")
+		return dbOrder, false
+
+	case result.Error != nil:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+		return dbOrder, false
+
+	case !dbOrder.DeletedAt.Valid:
+		errorhandling.ProblemResponse(w, http.StatusConflict, "Order is not deleted")
+		return dbOrder, false
+	}
+	return dbOrder, true
+}
+
+// orderKeyColumns are always selected for the orders, since they are needed to
+// preload the associations and to compute the ETag
+var orderKeyColumns = []string{"id", "version", "customer_id", "cake_id", "address_id"}
+
+// orderOutputs converts the orders to the output schema, as the lists of
+// orders are converted by writeList and exportSheet.
+func orderOutputs(dbOrders []models.Order) ([]schemas.OrderOutputSchema, error) {
+	return mappers.Orders(dbOrders), nil
+}
+
+// createOrder checks the customer and cake of the order, the availability of
+// the cake and the personalization, prices it, checks the allergies of the
+// customer and inserts it in a single transaction.
+func createOrder(db *gorm.DB, dbOrder *models.Order) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := checkOrderReferences(tx, &dbOrder.CustomerID, &dbOrder.CakeID); err != nil {
+			return err
+		}
+		if err := checkAvailability(tx, dbOrder); err != nil {
+			return err
+		}
+		if err := checkPersonalization(tx, dbOrder); err != nil {
+			return err
+		}
+		if err := priceVariant(tx, dbOrder); err != nil {
+			return err
+		}
+		if err := priceOrder(tx, dbOrder); err != nil {
+			return err
+		}
+		if err := checkAllergens(tx, dbOrder); err != nil {
+			return err
+		}
+		return tx.Create(dbOrder).Error
+	})
+}
+
+// patchOrder writes the merge patch on the order, within the given
+// transaction, after checking that the cake and customer exist and the
+// customer is active. The order is priced again when its customer, cake,
+// options, quantity or delivery change, so marking an order as delivered keeps the
+// total it was placed with, while the unit price is only computed again when
+// its cake or options change, and the allergies of the customer are checked
+// again when its customer or cake change, like its personalization when its
+// cake or personalization change and the availability of the cake when its
+// cake or due date change. Setting addressId switches the order to delivery.
+func patchOrder(tx *gorm.DB, dbOrder *models.Order, inputOrder schemas.OrderPatchInputSchema) error {
+	var customerID, cakeID *uint
+	if inputOrder.CustomerID.Set {
+		customerID = &inputOrder.CustomerID.Value
+	}
+	if inputOrder.CakeID.Set {
+		cakeID = &inputOrder.CakeID.Value
+	}
+	if err := checkOrderReferences(tx, customerID, cakeID); err != nil {
+		return err
+	}
+
+	updates := patch.Updates(inputOrder)
+	delete(updates, "Pickup")
+	delete(updates, "AddressID")
+	delete(updates, "OptionIDs")
+	delete(updates, "Personalization")
+	delete(updates, "DueDate")
+
+	if cakeID != nil || inputOrder.DueDate.Set {
+		checked := *dbOrder
+		if cakeID != nil {
+			checked.CakeID = *cakeID
+		}
+		if inputOrder.DueDate.Set {
+			checked.DueDate = optionalText(inputOrder.DueDate.Value)
+		}
+		if err := checkAvailability(tx, &checked); err != nil {
+			return err
+		}
+		updates["DueDate"] = checked.DueDate
+	}
+
+	if cakeID != nil || inputOrder.Personalization.Set {
+		checked := *dbOrder
+		if cakeID != nil {
+			checked.CakeID = *cakeID
+		}
+		if inputOrder.Personalization.Set {
+			checked.Personalization = jsonDocument(inputOrder.Personalization.Value)
+		}
+		if err := checkPersonalization(tx, &checked); err != nil {
+			return err
+		}
+		updates["Personalization"] = checked.Personalization
+	}
+
+	variantChanged := cakeID != nil || inputOrder.OptionIDs.Set
+	if variantChanged || customerID != nil || inputOrder.Qtd.Set || inputOrder.Pickup.Set || inputOrder.AddressID.Set {
+		priced := *dbOrder
+		if customerID != nil && *customerID != dbOrder.CustomerID {
+			// the address belongs to the former customer, so the order goes
+			// to the default address of the new one unless addressId is given
+			priced.CustomerID = *customerID
+			priced.AddressID = nil
+		}
+		if cakeID != nil {
+			priced.CakeID = *cakeID
+		}
+		if inputOrder.Qtd.Set {
+			priced.Qtd = inputOrder.Qtd.Value
+		}
+		if inputOrder.OptionIDs.Set {
+			priced.OptionIDs = inputOrder.OptionIDs.Value
+		}
+		if inputOrder.AddressID.Set {
+			if inputOrder.Pickup.Value && !inputOrder.AddressID.Null {
+				return errPickupWithAddress
+			}
+			priced.Pickup = false
+			priced.AddressID = nil
+			if !inputOrder.AddressID.Null {
+				priced.AddressID = &inputOrder.AddressID.Value
+			}
+		}
+		if inputOrder.Pickup.Set {
+			priced.Pickup = inputOrder.Pickup.Value
+		}
+
+		if variantChanged {
+			if err := priceVariant(tx, &priced); err != nil {
+				return err
+			}
+			updates["OptionIDs"] = priced.OptionIDs
+			updates["SKU"] = priced.SKU
+			updates["UnitPrice"] = priced.UnitPrice
+		}
+		if err := priceOrder(tx, &priced); err != nil {
+			return err
+		}
+		updates["Pickup"] = priced.Pickup
+		updates["AddressID"] = priced.AddressID
+		updates["DeliveryFee"] = priced.DeliveryFee
+		updates["Total"] = priced.Total
+	}
+
+	if customerID != nil || cakeID != nil {
+		checked := *dbOrder
+		if customerID != nil {
+			checked.CustomerID = *customerID
+		}
+		if cakeID != nil {
+			checked.CakeID = *cakeID
+		}
+		if err := checkAllergens(tx, &checked); err != nil {
+			return err
+		}
+		updates["AllergenWarnings"] = checked.AllergenWarnings
+	}
+
+	updated, err := updateVersioned(tx, dbOrder, dbOrder.Version, updates)
+	if err == nil && !updated {
+		return errVersionConflict
+	}
+	return err
+}
+
+// priceOrder sets the delivery address, the delivery fee and the total of
+// the order, from the unit price set by priceVariant. Orders without an
+// address are delivered to the default address of the customer, and picked
+// up when the customer has none. The fee is the one of the delivery zone of
+// the address, as chosen by deliveryZoneOf.
+func priceOrder(tx *gorm.DB, dbOrder *models.Order) error {
+	dbOrder.DeliveryFee = 0
+	if dbOrder.Pickup {
+		dbOrder.AddressID = nil
+	} else {
+		var address models.Address
+		query := tx.Where("customer_id = ?", dbOrder.CustomerID)
+		if dbOrder.AddressID != nil {
+			query = query.Where("id = ?", *dbOrder.AddressID)
+		} else {
+			query = query.Where("is_default = ?", true)
+		}
+
+		switch err := query.First(&address).Error; {
+		case err == gorm.ErrRecordNotFound && dbOrder.AddressID == nil:
+			dbOrder.Pickup = true
+		case err == gorm.ErrRecordNotFound:
+			return errAddressNotFound
+		case err != nil:
+			return err
+		default:
+			zone, err := deliveryZoneOf(tx, address)
+			if err != nil {
+				return err
+			}
+			dbOrder.AddressID = &address.ID
+			dbOrder.DeliveryFee = zone.Fee
+		}
+	}
+
+	dbOrder.Total = dbOrder.UnitPrice*uint64(dbOrder.Qtd) + dbOrder.DeliveryFee
+	return nil
+}
+
+// priceVariant checks that the options of the order belong to its cake and
+// hold one option of each kind the cake offers, and sets the unit price and
+// the SKU of the order, which are kept until its cake or options change. The
+// options are applied in the order of models.OptionKinds: the size may
+// replace the price of the cake, and the price deltas of every option are
+// then added to it. The SKU joins the SKUs of the options with hyphens, and
+// the option ids are stored, in that same order of kinds.
+func priceVariant(tx *gorm.DB, dbOrder *models.Order) error {
+	var cake models.Cake
+	switch err := tx.Select("id", "price").First(&cake, dbOrder.CakeID).Error; err {
+	case nil:
+		break
+	case gorm.ErrRecordNotFound:
+		return errCakeNotFound
+	default:
+		return err
+	}
+
+	var offered []models.CakeOption
+	if err := tx.Where("cake_id = ?", cake.ID).Find(&offered).Error; err != nil {
+		return err
+	}
+
+	chosen := make(map[string]models.CakeOption)
+	for _, optionID := range dbOrder.OptionIDs {
+		i := slices.IndexFunc(offered, func(option models.CakeOption) bool { return option.ID == optionID })
+		if i < 0 {
+			return errInvalidVariant
+		}
+		if _, repeated := chosen[offered[i].Kind]; repeated {
+			return errInvalidVariant
+		}
+		chosen[offered[i].Kind] = offered[i]
+	}
+
+	price := int64(cake.Price)
+	optionIDs := make(models.List[uint], 0, len(chosen))
+	var skus []string
+	for _, kind := range models.OptionKinds {
+		option, ok := chosen[kind]
+		if !ok {
+			if slices.ContainsFunc(offered, func(option models.CakeOption) bool { return option.Kind == kind }) {
+				return errInvalidVariant
+			}
+			continue
+		}
+
+		if option.Price != nil {
+			price = int64(*option.Price)
+		}
+		price += option.PriceDelta
+		optionIDs = append(optionIDs, option.ID)
+		skus = append(skus, option.SKU)
+	}
+	if price < 0 {
+		return errNegativePrice
+	}
+
+	dbOrder.OptionIDs = optionIDs
+	dbOrder.SKU = strings.Join(skus, "-")
+	dbOrder.UnitPrice = uint64(price)
+	return nil
+}
+
+// checkAvailability returns errCakeUnavailable when the cake of the order
+// cannot be ordered for its due date, or for today on the time zone of the
+// store when it has none, as described on orderableOn.
+func checkAvailability(tx *gorm.DB, dbOrder *models.Order) error {
+	day := storeToday()
+	if dbOrder.DueDate != nil {
+		var err error
+		if day, err = time.Parse(time.DateOnly, *dbOrder.DueDate); err != nil {
+			return err
+		}
+	}
+
+	switch err := tx.Scopes(orderableOn(day)).Select("id").First(&models.Cake{}, dbOrder.CakeID).Error; err {
+	case nil:
+		return nil
+	case gorm.ErrRecordNotFound:
+		return errCakeUnavailable
+	default:
+		return err
+	}
+}
+
+// checkPersonalization validates the personalization of the order against the
+// personalization schema of its cake, where orders without personalization
+// are validated as an empty object. The jsonschema.ValidationError listing the
+// rejected values is returned for invalid personalizations, and
+// errPersonalizationNotAccepted for the personalization of the cakes without
+// a schema.
+func checkPersonalization(tx *gorm.DB, dbOrder *models.Order) error {
+	var cake models.Cake
+	if err := tx.Select("id", "personalization_schema").First(&cake, dbOrder.CakeID).Error; err != nil {
+		return err
+	}
+
+	if len(cake.PersonalizationSchema) == 0 {
+		if len(dbOrder.Personalization) > 0 {
+			return errPersonalizationNotAccepted
+		}
+		return nil
+	}
+
+	schema, err := jsonschema.Parse(cake.PersonalizationSchema)
+	if err != nil {
+		return err
+	}
+	personalization := []byte(dbOrder.Personalization)
+	if len(personalization) == 0 {
+		personalization = []byte("{}")
+	}
+	return schema.Validate(personalization)
+}
+
+// checkAllergens compares the allergens of the cake of the order with the
+// allergies of its customer. Severe allergies return errSevereAllergy, while
+// the mild ones are listed on the allergen warnings of the order.
+func checkAllergens(tx *gorm.DB, dbOrder *models.Order) error {
+	var cake models.Cake
+	if err := tx.Select("id", "allergens").First(&cake, dbOrder.CakeID).Error; err != nil {
+		return err
+	}
+	var customer models.Customer
+	if err := tx.Select("id", "allergies").First(&customer, dbOrder.CustomerID).Error; err != nil {
+		return err
+	}
+
+	dbOrder.AllergenWarnings = models.List[string]{}
+	for _, allergy := range customer.Allergies {
+		switch {
+		case !slices.Contains(cake.Allergens, allergy.Allergen):
+			continue
+		case allergy.Severe:
+			return errSevereAllergy
+		default:
+			dbOrder.AllergenWarnings = append(dbOrder.AllergenWarnings, allergy.Allergen)
+		}
+	}
+	return nil
+}
+
+// createOrderError writes the problem response of an error returned by createOrder.
+func createOrderError(w http.ResponseWriter, err error) {
+	if !errorhandling.CheckSchemaError(err, "personalization", w) {
+		return
+	}
+	status, detail := createOrderProblem(err)
+	errorhandling.ProblemResponse(w, status, detail)
+}
+
+// createOrderProblem returns the status code and detail message of the
+// problem reporting an error written by createOrderError.
+func createOrderProblem(err error) (int, string) {
+	if err == gorm.ErrDuplicatedKey {
+		return http.StatusBadRequest, "Order already exists"
+	}
+	return orderReferencesProblem(err)
+}
+
+// createOrderItem validates and creates an order of an import, rejecting it
+// like CreateOrder.
+func createOrderItem(
+	w http.ResponseWriter, tx *gorm.DB, validator *validator.Validate, inputOrder schemas.OrderInputSchema,
+) schemas.BulkItemResult {
+	if err := validator.Struct(inputOrder); err != nil {
+		return rejectedItem(errorhandling.NewValidationProblem(w, err))
+	}
+
+	dbOrder := models.Order{
+		CustomerID: inputOrder.CustomerID,
+		CakeID:     inputOrder.CakeID,
+		Qtd:        inputOrder.Qtd,
+		Delivered:  inputOrder.Delivered,
+		Notes:      inputOrder.Notes,
+		Pickup:     inputOrder.Pickup,
+		AddressID:  inputOrder.AddressID,
+		OptionIDs:  inputOrder.OptionIDs,
+		DueDate:    inputOrder.DueDate,
+
+		Personalization: jsonDocument(inputOrder.Personalization),
+	}
+	if err := createOrder(tx, &dbOrder); err != nil {
+		if problem := errorhandling.NewSchemaProblem(w, err, "personalization"); problem != nil {
+			return rejectedItem(problem)
+		}
+		status, detail := createOrderProblem(err)
+		return rejectedItem(errorhandling.NewProblem(w, status, detail))
+	}
+	return schemas.BulkItemResult{ID: dbOrder.ID, Status: http.StatusCreated, Data: mappers.Order(dbOrder)}
+}
+
+// filterOrders applies the filters of the query string to the orders query:
+// ?status= takes "delivered" or "pending", and ?from= and ?to= limit the
+// creation date, given as RFC 3339 timestamps or as dates, in which case the
+// whole day of ?to= is included. If a filter is invalid, a 400 Bad Request
+// problem is written and false is returned.
+func filterOrders(w http.ResponseWriter, r *http.Request, query *gorm.DB) (*gorm.DB, bool) {
+	params := r.URL.Query()
+
+	switch params.Get("status") {
+	case "":
+		break
+	case "delivered":
+		query = query.Where("delivered = ?", true)
+	case "pending":
+		query = query.Where("delivered = ?", false)
+	default:
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid status parameter")
+		return nil, false
+	}
+
+	if value := params.Get("from"); value != "" {
+		from, _, err := parseDateParam(value)
+		if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid from parameter") {
+			return nil, false
+		}
+		query = query.Where("created_at >= ?", from)
+	}
+
+	if value := params.Get("to"); value != "" {
+		to, dateOnly, err := parseDateParam(value)
+		if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid to parameter") {
+			return nil, false
+		}
+		if dateOnly {
+			query = query.Where("created_at < ?", to.AddDate(0, 0, 1))
+		} else {
+			query = query.Where("created_at <= ?", to)
+		}
+	}
+
+	return query, true
+}
+
+// parseDateParam parses an RFC 3339 timestamp or a date, reporting whether
+// only the date was given.
+func parseDateParam(value string) (time.Time, bool, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, true, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, value)
+	return timestamp, false, err
+}
+
+// errors returned by the order transactions
+var (
+	errCustomerNotFound = errors.New("customer not found")
+	errCustomerInactive = errors.New("customer is inactive")
+	errCakeNotFound     = errors.New("cake not found")
+	errCakeArchived     = errors.New("cake archived")
+	errCakeUnavailable  = errors.New("cake unavailable on the due date")
+	errVersionConflict  = errors.New("version conflict")
+
+	errAddressNotFound      = errors.New("address not found")
+	errOutsideDeliveryZones = errors.New("address outside the delivery zones")
+	errPickupWithAddress    = errors.New("pickup order with an address")
+
+	// errInvalidVariant is returned for orders whose options do not belong to
+	// their cake, or do not hold one option of each kind the cake offers
+	errInvalidVariant = errors.New("invalid cake variant")
+	errNegativePrice  = errors.New("negative variant price")
+
+	// errPersonalizationNotAccepted is returned for the personalization of
+	// the orders of cakes without a personalization schema
+	errPersonalizationNotAccepted = errors.New("personalization not accepted")
+
+	// errSevereAllergy is returned for orders of cakes holding an allergen
+	// the customer is severely allergic to
+	errSevereAllergy = errors.New("severe allergy to the cake")
+)
+
+// checkOrderReferences checks, within the given transaction, that the
+// customer and cake referenced by an order exist and that the customer is
+// active. Nil ids are not checked.
+func checkOrderReferences(tx *gorm.DB, customerID, cakeID *uint) error {
+	if customerID != nil {
+		var customer models.Customer
+		switch err := tx.First(&customer, *customerID).Error; err {
+		case nil:
+			if !customer.Active {
+				return errCustomerInactive
+			}
+		case gorm.ErrRecordNotFound:
+			return errCustomerNotFound
+		default:
+			return err
+		}
+	}
+
+	if cakeID != nil {
+		var cake models.Cake
+		switch err := tx.First(&cake, *cakeID).Error; err {
+		case nil:
+			if cake.ArchivedAt != nil {
+				return errCakeArchived
+			}
+		case gorm.ErrRecordNotFound:
+			return errCakeNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOrderReferencesError writes the problem response of an error returned
+// by checkOrderReferences or by the foreign key constraints of the orders,
+// including the ones of checkPersonalization.
+func checkOrderReferencesError(w http.ResponseWriter, err error) {
+	if !errorhandling.CheckSchemaError(err, "personalization", w) {
+		return
+	}
+	status, detail := orderReferencesProblem(err)
+	errorhandling.ProblemResponse(w, status, detail)
+}
+
+// orderReferencesProblem returns the status code and detail message of the
+// problem reporting an error written by checkOrderReferencesError, including
+// the ones of priceOrder.
+func orderReferencesProblem(err error) (int, string) {
+	switch err {
+	case errCustomerNotFound, errCakeNotFound, gorm.ErrForeignKeyViolated:
+		return http.StatusBadRequest, "Customer or Cake not found"
+
+	case errCustomerInactive:
+		return http.StatusUnprocessableEntity, "Orders cannot be placed for inactive customers"
+
+	case errCakeArchived:
+		return http.StatusUnprocessableEntity, "Archived cakes cannot be ordered"
+
+	case errCakeUnavailable:
+		return http.StatusUnprocessableEntity, "The cake is not available on the due date"
+
+	case errAddressNotFound:
+		return http.StatusBadRequest, "Address not found for the customer"
+
+	case errPickupWithAddress:
+		return http.StatusBadRequest, "Pickup orders take no address"
+
+	case errOutsideDeliveryZones:
+		return http.StatusUnprocessableEntity, "The address is outside the delivery zones"
+
+	case errInvalidVariant:
+		return http.StatusBadRequest, "The options do not make a valid variant of the cake"
+
+	case errNegativePrice:
+		return http.StatusUnprocessableEntity, "The options make a negative price"
+
+	case errPersonalizationNotAccepted:
+		return http.StatusBadRequest, "The cake takes no personalization"
+
+	case errSevereAllergy:
+		return http.StatusUnprocessableEntity, "The cake holds an allergen the customer is severely allergic to"
 
 	default:
-		httphelpers.JsonResponse(
-			w,
-			http.StatusInternalServerError,
-			schemas.Message{
-				Code:   http.StatusInternalServerError,
-				Detail: []string{"Internal server error"},
-			},
-		)
+		return http.StatusInternalServerError, "Internal server error"
 	}
 }
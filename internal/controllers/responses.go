@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"gorm.io/gorm"
+)
+
+// listBatchSize is the number of records read at a time by the list endpoints
+const listBatchSize = 100
+
+// respond encodes the data with the given status code in the media type
+// negotiated by httphelpers.Respond: JSON, CSV or XML. If the request
+// accepts none of them, a 406 Not Acceptable problem is written instead.
+func respond(w http.ResponseWriter, r *http.Request, status int, data any) {
+	if errors.Is(httphelpers.Respond(w, r, status, data), httphelpers.ErrNotAcceptable) {
+		errorhandling.ProblemResponse(w, http.StatusNotAcceptable, "The resource is only available as JSON, CSV or XML")
+	}
+}
+
+// respondVersion encodes the data of a resource at the given version as
+// described on httphelpers.RespondVersion, tagging it with the ETag of the
+// representation. If the request accepts no media type of the API, a 406 Not
+// Acceptable problem is written instead.
+func respondVersion(w http.ResponseWriter, r *http.Request, version uint, data any) {
+	switch err := httphelpers.RespondVersion(w, r, version, data); {
+	case errors.Is(err, httphelpers.ErrNotAcceptable):
+		errorhandling.ProblemResponse(w, http.StatusNotAcceptable, "The resource is only available as JSON, CSV or XML")
+	case err != nil:
+		errorhandling.ProblemResponse(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// writeList streams the records of the model M found by the query as a 200
+// OK response, converting them to the output schema O in batches and
+// restricting them to the fieldset, so only a batch of records is held in
+// memory at a time. The records are read in the order of their IDs.
+func writeList[M, O any](
+	w http.ResponseWriter, r *http.Request, fields *fieldset, query *gorm.DB, convert func([]M) ([]O, error),
+) {
+	var zero O
+	respond(w, r, http.StatusOK, httphelpers.Stream{
+		Of: fields.header(r, zero),
+		Each: func(yield func(item any) error) error {
+			var batch []M
+			return query.FindInBatches(&batch, listBatchSize, func(*gorm.DB, int) error {
+				outputs, err := convert(batch)
+				if err != nil {
+					return err
+				}
+				for _, output := range outputs {
+					if err := yield(fields.pick(r, output)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+		},
+	})
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/mappers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+	"github.com/LeandroDeJesus-S/confectionery/internal/search"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"gorm.io/gorm"
+)
+
+// Number of results returned for each type when ?limit= is not set, and the
+// largest one accepted
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+)
+
+type SearchController struct {
+	db     *gorm.DB
+	engine *search.Engine
+}
+
+func NewSearchController(db *gorm.DB, engine *search.Engine) *SearchController {
+	return &SearchController{db: db, engine: engine}
+}
+
+// Search looks the query of ?q= up on the active customers, the cakes that can
+// be ordered today, like the ones listed on GET /cakes/, and the notes of the
+// orders, ignoring case and accents, and returns the results grouped by type,
+// best ranked first, as a JSON response with a 200 OK status code. ?limit=
+// sets the number of results of each type.
+//
+// If the query is missing or the limit is invalid, the function will return a 400 Bad Request
+// response. If there are any server errors, it returns a 500 Internal Server Error response.
+func (c *SearchController) Search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Missing search query")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		limit = parsed
+	}
+
+	var customers []models.Customer
+	err := c.find(query, search.TypeCustomer, limit, c.db, &customers, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("active = ?", true)
+	})
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+
+	var cakes []models.Cake
+	err = c.find(query, search.TypeCake, limit, preloadImages(c.db), &cakes, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("archived_at IS NULL")
+	}, orderableOn(storeToday()))
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+
+	var orders []models.Order
+	err = c.find(query, search.TypeOrder, limit, c.db, &orders)
+	if !errorhandling.CheckOrHttpError(err, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+
+	result := schemas.SearchOutputSchema{
+		Customers: make([]schemas.CustomerOutputSchema, 0, len(customers)),
+		Cakes:     mappers.Cakes(cakes),
+		Orders:    mappers.Orders(orders),
+	}
+	for _, customer := range customers {
+		result.Customers = append(result.Customers, mappers.Customer(customer, nil))
+	}
+	respond(w, r, http.StatusOK, result)
+}
+
+// find loads the records of the hits of the given type into dest, a pointer
+// to a slice of models, keeping the order of the ranking. The hits are
+// restricted to the records selected by the scopes, such as the ones not
+// deleted, before they are limited, and the records are loaded with query.
+func (c *SearchController) find(
+	q string, docType string, limit int, query *gorm.DB, dest any, scopes ...func(*gorm.DB) *gorm.DB,
+) error {
+	candidates := c.db.Model(dest).Scopes(scopes...).Select("id")
+	hits, err := c.engine.Search(q, docType, limit, candidates)
+	if err != nil || len(hits) == 0 {
+		return err
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	// the ids are listed in ranking order, which CASE keeps on the result
+	var order strings.Builder
+	order.WriteString("CASE id")
+	for i, id := range ids {
+		order.WriteString(" WHEN " + strconv.FormatUint(uint64(id), 10) + " THEN " + strconv.Itoa(i))
+	}
+	order.WriteString(" END")
+
+	return query.Scopes(scopes...).Where("id IN ?", ids).Order(order.String()).Find(dest).Error
+}
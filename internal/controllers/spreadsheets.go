@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/mappers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/spreadsheet"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// maxImportRows is the number of rows accepted by a single import
+const maxImportRows = 5000
+
+// maxImportSize is the size in bytes of the largest spreadsheet imported
+const maxImportSize = 10 << 20
+
+// exportBatchSize is the number of records read at a time by the exports
+const exportBatchSize = 500
+
+// SpreadsheetController imports and exports the customers, cakes and orders
+// as CSV and XLSX spreadsheets.
+type SpreadsheetController struct {
+	db        *gorm.DB
+	validator *validator.Validate
+}
+
+// NewSpreadsheetController initializes the SpreadsheetController structure
+func NewSpreadsheetController(db *gorm.DB, validator *validator.Validate) *SpreadsheetController {
+	return &SpreadsheetController{db: db, validator: validator}
+}
+
+// ImportCustomers creates a customer from each row of the spreadsheet, as
+// described on importSheet. The rows are validated and rejected like on
+// CreateCustomer.
+func (c *SpreadsheetController) ImportCustomers(w http.ResponseWriter, r *http.Request) {
+	importSheet(w, r, c.db, func(tx *gorm.DB, inpCustomer schemas.CustomerInputSchema) schemas.BulkItemResult {
+		return createCustomerItem(w, tx, c.validator, inpCustomer)
+	})
+}
+
+// ImportCakes creates a cake from each row of the spreadsheet, as described
+// on importSheet. The rows are validated and rejected like on CreateCake.
+func (c *SpreadsheetController) ImportCakes(w http.ResponseWriter, r *http.Request) {
+	importSheet(w, r, c.db, func(tx *gorm.DB, inputCake schemas.CakeInputSchema) schemas.BulkItemResult {
+		return createCakeItem(w, tx, c.validator, inputCake)
+	})
+}
+
+// ImportOrders creates an order from each row of the spreadsheet, as
+// described on importSheet. The rows are validated and rejected like on
+// CreateOrder.
+func (c *SpreadsheetController) ImportOrders(w http.ResponseWriter, r *http.Request) {
+	importSheet(w, r, c.db, func(tx *gorm.DB, inputOrder schemas.OrderInputSchema) schemas.BulkItemResult {
+		return createOrderItem(w, tx, c.validator, inputOrder)
+	})
+}
+
+// ExportCustomers streams every customer, including the deleted ones, with
+// their stats, as described on exportSheet.
+func (c *SpreadsheetController) ExportCustomers(w http.ResponseWriter, r *http.Request) {
+	exportSheet(w, r, c.db, "customers", func(dbCustomers []models.Customer) ([]schemas.CustomerOutputSchema, error) {
+		return customerOutputs(c.db, dbCustomers)
+	})
+}
+
+// ExportCakes streams every cake, including the archived ones, as described
+// on exportSheet.
+func (c *SpreadsheetController) ExportCakes(w http.ResponseWriter, r *http.Request) {
+	exportSheet(w, r, c.db, "cakes", func(dbCakes []models.Cake) ([]schemas.CakeOutputSchema, error) {
+		return mappers.Cakes(dbCakes), nil
+	})
+}
+
+// ExportOrders streams the orders that were not deleted, as described on
+// exportSheet.
+func (c *SpreadsheetController) ExportOrders(w http.ResponseWriter, r *http.Request) {
+	exportSheet(w, r, c.db, "orders", orderOutputs)
+}
+
+// importSheet reads the CSV or XLSX spreadsheet of the request, sent as the
+// body or as the "file" field of a multipart form, and creates a record from
+// each row. The first row holds the names of the columns, the JSON names of
+// the fields of the input schema T in any case, and blank rows are skipped.
+// Columns unknown to T are ignored, so exported spreadsheets can be imported
+// back.
+//
+// The rows are processed like the items of the bulk endpoints, with the same
+// ?mode= and ?dryRun= parameters, as described on executeBulk, and the result
+// of each one carries its line on the spreadsheet.
+func importSheet[T any](
+	w http.ResponseWriter, r *http.Request, db *gorm.DB, create func(tx *gorm.DB, input T) schemas.BulkItemResult,
+) {
+	options, ok := parseBulkOptions(w, r)
+	if !ok {
+		return
+	}
+
+	rows, ok := readSheet(w, r)
+	if !ok {
+		return
+	}
+
+	// lines holds the line of each data row, starting from 1
+	var header []string
+	var lines []int
+	for i, row := range rows {
+		switch {
+		case spreadsheet.Blank(row):
+			continue
+		case header == nil:
+			header = row
+		default:
+			lines = append(lines, i+1)
+		}
+	}
+
+	if len(lines) == 0 {
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "The spreadsheet has no rows")
+		return
+	}
+	if len(lines) > maxImportRows {
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Imports take up to 5000 rows")
+		return
+	}
+
+	executeBulk(w, r, db, options, len(lines), http.StatusCreated, func(tx *gorm.DB, i int) schemas.BulkItemResult {
+		var result schemas.BulkItemResult
+
+		var input T
+		var decodeErr *spreadsheet.DecodeError
+		switch err := spreadsheet.Decode(header, rows[lines[i]-1], &input); {
+		case errors.As(err, &decodeErr):
+			problem := errorhandling.NewProblem(w, http.StatusBadRequest, "Invalid cell values")
+			problem.InvalidFields = decodeErr.Columns
+			result = rejectedItem(problem)
+		case err != nil:
+			result = rejectedItem(errorhandling.NewProblem(w, http.StatusBadRequest, "Invalid cell values"))
+		default:
+			result = create(tx, input)
+		}
+
+		result.Row = lines[i]
+		return result
+	})
+}
+
+// readSheet reads the rows of the spreadsheet of the request. If the request
+// has no CSV or XLSX spreadsheet, a problem response is written and false is
+// returned.
+func readSheet(w http.ResponseWriter, r *http.Request) ([][]string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		errorhandling.ProblemResponse(w, http.StatusUnsupportedMediaType, "Unsupported spreadsheet format")
+		return nil, false
+	}
+
+	body := r.Body
+	filename := ""
+	if mediaType == "multipart/form-data" {
+		file, fileHeader, err := r.FormFile("file")
+		if !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid spreadsheet") {
+			return nil, false
+		}
+		defer file.Close()
+
+		body = file
+		filename = fileHeader.Filename
+		mediaType, _, _ = mime.ParseMediaType(fileHeader.Header.Get("Content-Type"))
+	}
+
+	format, err := spreadsheet.FormatOf(mediaType, filename)
+	if err != nil {
+		errorhandling.ProblemResponse(w, http.StatusUnsupportedMediaType, "Unsupported spreadsheet format")
+		return nil, false
+	}
+
+	// the header takes a line besides the rows
+	rows, err := spreadsheet.ReadAll(format, body, maxImportRows+1)
+	switch {
+	case errors.Is(err, spreadsheet.ErrTooManyRows):
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Imports take up to 5000 rows")
+		return nil, false
+	case errors.Is(err, spreadsheet.ErrTooLarge):
+		errorhandling.ProblemResponse(w, http.StatusRequestEntityTooLarge, "The spreadsheet is too large")
+		return nil, false
+	case !errorhandling.CheckOrHttpError(err, w, http.StatusBadRequest, "Invalid spreadsheet"):
+		return nil, false
+	}
+	return rows, true
+}
+
+// exportSheet streams the records of the model M as a spreadsheet named
+// after the resource, converting them to the output schema O in batches.
+// The format is chosen with ?format=, "csv", the default, or "xlsx", and the
+// columns are the ones of the JSON output, without the embedded resources.
+func exportSheet[M, O any](
+	w http.ResponseWriter, r *http.Request, db *gorm.DB, resource string, convert func([]M) ([]O, error),
+) {
+	format, extension := spreadsheet.CSV, "csv"
+	switch r.URL.Query().Get("format") {
+	case "", "csv":
+		break
+	case "xlsx":
+		format, extension = spreadsheet.XLSX, "xlsx"
+	default:
+		errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid format parameter")
+		return
+	}
+
+	contentType := format
+	if format == spreadsheet.CSV {
+		contentType += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(
+		"attachment", map[string]string{"filename": resource + "." + extension},
+	))
+
+	// the rows are streamed, so errors past this point can only be logged
+	writer, err := spreadsheet.NewWriter(format, w)
+	if err != nil {
+		log.Println("Cannot export the", resource, err)
+		return
+	}
+
+	var zero O
+	var header []any
+	for _, name := range spreadsheet.Header(zero) {
+		header = append(header, name)
+	}
+	err = writer.Write(header)
+
+	var batch []M
+	if err == nil {
+		err = db.FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			outputs, err := convert(batch)
+			if err != nil {
+				return err
+			}
+			for _, output := range outputs {
+				if err := writer.Write(spreadsheet.Values(output)); err != nil {
+					return err
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			http.NewResponseController(w).Flush()
+			return nil
+		}).Error
+	}
+
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Println("Cannot export the", resource, err)
+	}
+}
@@ -0,0 +1,12 @@
+package controllers
+
+import "gorm.io/gorm"
+
+// updateVersioned writes the updates on the record only if it still has the
+// version read by the request, incrementing it. It reports whether the record
+// was updated, which is false when another request changed it in between.
+func updateVersioned(db *gorm.DB, model any, version uint, updates map[string]any) (bool, error) {
+	updates["Version"] = version + 1
+	result := db.Model(model).Where("version = ?", version).Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,124 @@
+package i18n
+
+var ptBRCatalog = map[string]string{
+	// problem titles
+	"Bad Request":              "Requisição inválida",
+	"Not Found":                "Não encontrado",
+	"Method Not Allowed":       "Método não permitido",
+	"Conflict":                 "Conflito",
+	"Internal Server Error":    "Erro interno do servidor",
+	"Validation failed":        "Falha na validação",
+	"Unsupported Media Type":   "Tipo de mídia não suportado",
+	"Precondition Failed":      "Pré-condição falhou",
+	"Unprocessable Entity":     "Entidade não processável",
+	"Unauthorized":             "Não autorizado",
+	"Forbidden":                "Proibido",
+	"Failed Dependency":        "Dependência falhou",
+	"Not Acceptable":           "Não aceitável",
+	"Request Entity Too Large": "Entidade muito grande",
+
+	// problem details
+	"One or more fields are invalid": "Um ou mais campos são inválidos",
+	"Resource not found":             "Recurso não encontrado",
+	"Method not allowed":             "Método não permitido",
+	"The request body exceeds 1 MB":  "O corpo da requisição excede 1 MB",
+	"Invalid input":                  "Entrada inválida",
+	"Admin endpoints are disabled":   "Endpoints administrativos estão desabilitados",
+	"Admin credentials are required": "Credenciais de administrador são necessárias",
+	"Internal server error":          "Erro interno do servidor",
+	"Unexpected error":               "Erro inesperado",
+	"Something went wrong":           "Algo deu errado",
+	"Patch test operation failed":    "A operação test do patch falhou",
+
+	"The resource was modified by another request": "O recurso foi modificado por outra requisição",
+
+	"Invalid mode parameter":                  "Parâmetro mode inválido",
+	"Bulk requests take from 1 to 100 items":  "Requisições em lote aceitam de 1 a 100 itens",
+	"Rolled back because another item failed": "Desfeito porque outro item falhou",
+	"Invalid dryRun parameter":                "Parâmetro dryRun inválido",
+
+	"Unsupported spreadsheet format": "Formato de planilha não suportado",
+	"Invalid spreadsheet":            "Planilha inválida",
+	"The spreadsheet has no rows":    "A planilha não possui linhas",
+	"Imports take up to 5000 rows":   "Importações aceitam até 5000 linhas",
+	"The spreadsheet is too large":   "A planilha é grande demais",
+	"Invalid cell values":            "Valores de células inválidos",
+	"Invalid format parameter":       "Parâmetro format inválido",
+
+	"Invalid idempotency key":                                      "Chave de idempotência inválida",
+	"Idempotency key was already used with a different payload":    "A chave de idempotência já foi utilizada com outro conteúdo",
+	"A request with this idempotency key is still being processed": "Uma requisição com esta chave de idempotência ainda está sendo processada",
+
+	"Invalid user id":                        "ID de usuário inválido",
+	"Invalid customer ID":                    "ID de cliente inválido",
+	"Customer not found":                     "Cliente não encontrado",
+	"Customer is not deleted":                "O cliente não está removido",
+	"Customer is referenced by orders":       "O cliente possui pedidos associados",
+	"Missing search query":                   "Consulta de busca ausente",
+	"Invalid limit parameter":                "Parâmetro limit inválido",
+	"Invalid includeDeleted parameter":       "Parâmetro includeDeleted inválido",
+	"Invalid tags parameter":                 "Parâmetro tags inválido",
+	"Invalid excludeAllergens parameter":     "Parâmetro excludeAllergens inválido",
+	"Email already exists":                   "E-mail já cadastrado",
+	"CPF already exists":                     "CPF já cadastrado",
+	"The customer needs an email or a phone": "O cliente precisa de um e-mail ou de um telefone",
+	"Error creating customer":                "Erro ao criar cliente",
+	"Error updating customer":                "Erro ao atualizar cliente",
+	"Email or CPF already exists":            "E-mail ou CPF já cadastrado",
+
+	"Invalid cake id":                                    "ID de bolo inválido",
+	"Cake not found":                                     "Bolo não encontrado",
+	"Cake already exists":                                "Bolo já cadastrado",
+	"Cake is referenced by orders":                       "O bolo possui pedidos associados",
+	"Cake is not archived":                               "O bolo não está arquivado",
+	"Invalid availableOn parameter":                      "Parâmetro availableOn inválido",
+	"Invalid category parameter":                         "Parâmetro category inválido",
+	"The availability ends before it starts":             "A disponibilidade termina antes de começar",
+	"Invalid include parameter":                          "Parâmetro include inválido",
+	"The resource is only available as JSON, CSV or XML": "O recurso só está disponível como JSON, CSV ou XML",
+	"Unknown fields requested":                           "Campos desconhecidos solicitados",
+
+	"Invalid option id":                               "ID de opção inválido",
+	"Option not found":                                "Opção não encontrada",
+	"Option already exists":                           "Opção já cadastrada",
+	"SKU already exists":                              "SKU já cadastrado",
+	"Only size options replace the price of the cake": "Apenas opções de tamanho substituem o preço do bolo",
+
+	"Invalid image id": "ID de imagem inválido",
+	"Image not found":  "Imagem não encontrada",
+	"Images must be sent as multipart/form-data": "As imagens devem ser enviadas como multipart/form-data",
+	"The images exceed 20 MB":                    "As imagens excedem 20 MB",
+	"No images were sent":                        "Nenhuma imagem foi enviada",
+	"Up to 10 images are sent at once":           "São enviadas até 10 imagens por vez",
+	"Only JPEG, PNG and GIF images are accepted": "Apenas imagens JPEG, PNG e GIF são aceitas",
+	"Images must have up to 24 megapixels":       "As imagens devem ter até 24 megapixels",
+	"Invalid image":                              "Imagem inválida",
+
+	"Invalid Order id":           "ID de pedido inválido",
+	"Order not found":            "Pedido não encontrado",
+	"Order is not deleted":       "O pedido não está removido",
+	"Invalid status parameter":   "Parâmetro status inválido",
+	"Invalid from parameter":     "Parâmetro from inválido",
+	"Invalid to parameter":       "Parâmetro to inválido",
+	"Order already exists":       "Pedido já cadastrado",
+	"Customer or Cake not found": "Cliente ou bolo não encontrado",
+
+	"Orders cannot be placed for inactive customers":                  "Não é possível registrar pedidos para clientes inativos",
+	"The cake is not available on the due date":                       "O bolo não está disponível na data de entrega",
+	"Archived cakes cannot be ordered":                                "Bolos arquivados não podem ser pedidos",
+	"Address not found for the customer":                              "Endereço não encontrado para o cliente",
+	"Pickup orders take no address":                                   "Pedidos para retirada não recebem endereço",
+	"The address is outside the delivery zones":                       "O endereço está fora das zonas de entrega",
+	"The cake holds an allergen the customer is severely allergic to": "O bolo contém um alérgeno ao qual o cliente tem alergia grave",
+	"The options do not make a valid variant of the cake":             "As opções não formam uma variação válida do bolo",
+	"The cake takes no personalization":                               "O bolo não aceita personalização",
+	"The options make a negative price":                               "As opções resultam em um preço negativo",
+
+	"Invalid address ID": "ID de endereço inválido",
+	"Address not found":  "Endereço não encontrado",
+
+	"Invalid delivery zone":        "Zona de entrega inválida",
+	"Invalid delivery zone id":     "ID de zona de entrega inválido",
+	"Delivery zone not found":      "Zona de entrega não encontrada",
+	"Delivery zone already exists": "Zona de entrega já cadastrada",
+}
@@ -0,0 +1,297 @@
+package i18n
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/jsonschema"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/pt_BR"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	pt_BR_translations "github.com/go-playground/validator/v10/translations/pt_BR"
+)
+
+// Supported languages, as sent on the Content-Language header
+const (
+	English             = "en"
+	BrazilianPortuguese = "pt-BR"
+)
+
+// locales maps the supported languages to the universal-translator locales
+var locales = map[string]string{
+	English:             "en",
+	BrazilianPortuguese: "pt_BR",
+}
+
+// catalogs holds the translations of the API messages, keyed by the
+// English message. English needs no catalog since the keys are the messages.
+var catalogs = map[string]map[string]string{
+	BrazilianPortuguese: ptBRCatalog,
+}
+
+var universal = ut.New(en.New(), en.New(), pt_BR.New())
+
+func init() {
+	for lang, catalog := range catalogs {
+		trans := translator(lang)
+		for key, text := range catalog {
+			if err := trans.Add(key, text, false); err != nil {
+				log.Fatalf("i18n: cannot add %q to the %s catalog: %v", key, lang, err)
+			}
+		}
+	}
+}
+
+// DefaultLanguage returns the language used when the client does not accept
+// any of the supported ones, configured by the DEFAULT_LANGUAGE environment
+// variable and falling back to Brazilian Portuguese.
+func DefaultLanguage() string {
+	if lang, ok := supported(os.Getenv("DEFAULT_LANGUAGE")); ok {
+		return lang
+	}
+	return BrazilianPortuguese
+}
+
+// Negotiate picks the supported language that best matches the given
+// Accept-Language header, honouring the quality values. Generic tags such as
+// "pt" match the regional variant supported by the API.
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for part := range strings.SplitSeq(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if lang, ok := supported(c.tag); ok {
+			return lang
+		}
+	}
+	return DefaultLanguage()
+}
+
+// supported maps a language tag to the supported language it denotes.
+func supported(tag string) (string, bool) {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(tag, "_", "-")), "-")
+	switch primary {
+	case "en":
+		return English, true
+	case "pt":
+		return BrazilianPortuguese, true
+	default:
+		return "", false
+	}
+}
+
+// translator returns the universal-translator of the given language,
+// falling back to English.
+func translator(lang string) ut.Translator {
+	trans, _ := universal.GetTranslator(locales[lang])
+	return trans
+}
+
+// T translates the message to the given language. Messages missing from the
+// catalog are returned untranslated.
+func T(lang, message string, params ...string) string {
+	text, err := translator(lang).T(message, params...)
+	if err != nil {
+		return message
+	}
+	return text
+}
+
+// TranslateFieldError translates the error of a field rejected by the
+// validator to the given language.
+func TranslateFieldError(lang string, fieldErr validator.FieldError) string {
+	return fieldErr.Translate(translator(lang))
+}
+
+// customValidations holds the messages of the validation tags registered by
+// the API, and of the ones missing from the default translations, by
+// language, where {0} stands for the field
+var customValidations = map[string]map[string]string{
+	"required_without": {
+		English:             "{0} is a required field",
+		BrazilianPortuguese: "{0} é um campo obrigatório",
+	},
+	"required_with": {
+		English:             "{0} is a required field",
+		BrazilianPortuguese: "{0} é um campo obrigatório",
+	},
+	"excluded_if": {
+		English:             "{0} must be left out",
+		BrazilianPortuguese: "{0} deve ser omitido",
+	},
+	"excluded_unless": {
+		English:             "{0} must be left out",
+		BrazilianPortuguese: "{0} deve ser omitido",
+	},
+	"cep": {
+		English:             "{0} must be a valid CEP, such as 01310-100",
+		BrazilianPortuguese: "{0} deve ser um CEP válido, como 01310-100",
+	},
+	"phone": {
+		English:             "{0} must be a valid phone number, such as +55 11 91234-5678",
+		BrazilianPortuguese: "{0} deve ser um telefone válido, como +55 11 91234-5678",
+	},
+	"cpf": {
+		English:             "{0} must be a valid CPF, such as 123.456.789-09",
+		BrazilianPortuguese: "{0} deve ser um CPF válido, como 123.456.789-09",
+	},
+	"tag": {
+		English:             "{0} must have up to 30 letters, digits, spaces or hyphens",
+		BrazilianPortuguese: "{0} deve ter até 30 letras, números, espaços ou hífens",
+	},
+	"allergen": {
+		English:             "{0} must be one of " + strings.Join(models.Allergens, ", "),
+		BrazilianPortuguese: "{0} deve ser um de " + strings.Join(models.Allergens, ", "),
+	},
+	"diet": {
+		English:             "{0} must be one of " + strings.Join(models.Diets, ", "),
+		BrazilianPortuguese: "{0} deve ser um de " + strings.Join(models.Diets, ", "),
+	},
+	"weekday": {
+		English:             "{0} must be one of " + strings.Join(models.Weekdays, ", "),
+		BrazilianPortuguese: "{0} deve ser um de " + strings.Join(models.Weekdays, ", "),
+	},
+	"date": {
+		English:             "{0} must be a date such as 2024-12-24",
+		BrazilianPortuguese: "{0} deve ser uma data como 2024-12-24",
+	},
+	"jsonschema": {
+		English:             "{0} must be the JSON Schema of an object, using the keywords " + strings.Join(jsonschemaKeywords, ", "),
+		BrazilianPortuguese: "{0} deve ser o JSON Schema de um objeto, com as palavras-chave " + strings.Join(jsonschemaKeywords, ", "),
+	},
+	"contact": {
+		English:             "an email or a phone is required to contact the customer",
+		BrazilianPortuguese: "é necessário um email ou um telefone para contatar o cliente",
+	},
+}
+
+// jsonschemaKeywords lists the JSON Schema keywords accepted by jsonschema.Parse
+var jsonschemaKeywords = []string{
+	"type", "enum", "minLength", "maxLength", "pattern", "format", "minimum", "maximum",
+	"items", "minItems", "maxItems", "properties", "required", "additionalProperties",
+}
+
+// schemaMessages holds the messages of the values rejected by the JSON Schema
+// keywords, by language, where {0} stands for the path of the value and {1}
+// for the value of the keyword
+var schemaMessages = map[string]map[string]string{
+	"type": {
+		English:             "{0} must be of type {1}",
+		BrazilianPortuguese: "{0} deve ser do tipo {1}",
+	},
+	"enum": {
+		English:             "{0} must be one of {1}",
+		BrazilianPortuguese: "{0} deve ser um de {1}",
+	},
+	"minLength": {
+		English:             "{0} must be at least {1} characters long",
+		BrazilianPortuguese: "{0} deve ter pelo menos {1} caracteres",
+	},
+	"maxLength": {
+		English:             "{0} must be at most {1} characters long",
+		BrazilianPortuguese: "{0} deve ter no máximo {1} caracteres",
+	},
+	"pattern": {
+		English:             "{0} must match {1}",
+		BrazilianPortuguese: "{0} deve corresponder a {1}",
+	},
+	"format": {
+		English:             "{0} must be in the {1} format",
+		BrazilianPortuguese: "{0} deve estar no formato {1}",
+	},
+	"minimum": {
+		English:             "{0} must be {1} or greater",
+		BrazilianPortuguese: "{0} deve ser {1} ou maior",
+	},
+	"maximum": {
+		English:             "{0} must be {1} or less",
+		BrazilianPortuguese: "{0} deve ser {1} ou menor",
+	},
+	"minItems": {
+		English:             "{0} must contain at least {1} items",
+		BrazilianPortuguese: "{0} deve conter pelo menos {1} itens",
+	},
+	"maxItems": {
+		English:             "{0} must contain at most {1} items",
+		BrazilianPortuguese: "{0} deve conter no máximo {1} itens",
+	},
+	"required": {
+		English:             "{0} is a required field",
+		BrazilianPortuguese: "{0} é um campo obrigatório",
+	},
+	"additionalProperties": {
+		English:             "{0} is not an accepted field",
+		BrazilianPortuguese: "{0} não é um campo aceito",
+	},
+}
+
+// TranslateSchemaError translates the error of a value rejected by a JSON
+// Schema to the given language, naming the value by the given path.
+func TranslateSchemaError(lang, path string, schemaErr jsonschema.Error) string {
+	messages, ok := schemaMessages[schemaErr.Keyword]
+	if !ok {
+		return schemaErr.Error()
+	}
+	message, ok := messages[lang]
+	if !ok {
+		message = messages[English]
+	}
+	return strings.NewReplacer("{0}", path, "{1}", schemaErr.Param).Replace(message)
+}
+
+// RegisterValidatorTranslations registers the default validator messages of
+// every supported language on the given validator, along with the messages
+// of the validation tags registered by the API.
+func RegisterValidatorTranslations(v *validator.Validate) error {
+	if err := en_translations.RegisterDefaultTranslations(v, translator(English)); err != nil {
+		return err
+	}
+	if err := pt_BR_translations.RegisterDefaultTranslations(v, translator(BrazilianPortuguese)); err != nil {
+		return err
+	}
+
+	for tag, messages := range customValidations {
+		for lang, message := range messages {
+			trans := translator(lang)
+			err := v.RegisterTranslation(tag, trans, func(ut ut.Translator) error {
+				return ut.Add(tag, message, true)
+			}, func(ut ut.Translator, fe validator.FieldError) string {
+				text, _ := ut.T(fe.Tag(), fe.Field())
+				return text
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package mappers
+
+import (
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+)
+
+// Address converts the address model to its output schema.
+func Address(address models.Address) schemas.AddressOutputSchema {
+	return schemas.AddressOutputSchema{
+		ID:           address.ID,
+		CustomerID:   address.CustomerID,
+		Label:        address.Label,
+		Street:       address.Street,
+		Number:       address.Number,
+		Complement:   address.Complement,
+		Neighborhood: address.Neighborhood,
+		City:         address.City,
+		CEP:          cep(address.CEP),
+		Default:      address.Default,
+	}
+}
+
+// Addresses converts the address models to their output schema.
+func Addresses(addresses []models.Address) []schemas.AddressOutputSchema {
+	out := make([]schemas.AddressOutputSchema, 0, len(addresses))
+	for _, address := range addresses {
+		out = append(out, Address(address))
+	}
+	return out
+}
+
+// DeliveryZone converts the delivery zone model to its output schema.
+func DeliveryZone(zone models.DeliveryZone) schemas.DeliveryZoneOutputSchema {
+	return schemas.DeliveryZoneOutputSchema{
+		ID:           zone.ID,
+		Name:         zone.Name,
+		CEPStart:     cep(zone.CEPStart),
+		CEPEnd:       cep(zone.CEPEnd),
+		Neighborhood: zone.Neighborhood,
+		City:         zone.City,
+		Fee:          zone.Fee,
+	}
+}
+
+// DeliveryZones converts the delivery zone models to their output schema.
+func DeliveryZones(zones []models.DeliveryZone) []schemas.DeliveryZoneOutputSchema {
+	out := make([]schemas.DeliveryZoneOutputSchema, 0, len(zones))
+	for _, zone := range zones {
+		out = append(out, DeliveryZone(zone))
+	}
+	return out
+}
+
+// cep formats the digits of a stored CEP as 01310-100.
+func cep(digits string) string {
+	if len(digits) != 8 {
+		return digits
+	}
+	return digits[:5] + "-" + digits[5:]
+}
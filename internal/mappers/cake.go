@@ -0,0 +1,70 @@
+package mappers
+
+import (
+	"encoding/json"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+)
+
+// Cake converts the cake model to its output schema, embedding its images,
+// and its options when they were preloaded.
+func Cake(cake models.Cake) schemas.CakeOutputSchema {
+	out := schemas.CakeOutputSchema{
+		ID:         cake.ID,
+		Name:       cake.Name,
+		Price:      cake.Price,
+		Allergens:  list(cake.Allergens),
+		Nutrition:  nutrition(cake.Nutrition),
+		ArchivedAt: optionalTimestamp(cake.ArchivedAt),
+
+		PersonalizationSchema: document(cake.PersonalizationSchema),
+		Category:              cake.Category,
+		Tags:                  list(cake.Tags),
+		Available:             cake.Available == nil || *cake.Available,
+		AvailableFrom:         cake.AvailableFrom,
+		AvailableUntil:        cake.AvailableUntil,
+		Weekdays:              list(cake.Weekdays),
+	}
+	if cake.Options != nil {
+		out.Options = CakeOptions(cake.Options)
+	}
+	out.Images = CakeImages(cake.Images)
+	return out
+}
+
+// Cakes converts the cake models to their output schema.
+func Cakes(cakes []models.Cake) []schemas.CakeOutputSchema {
+	out := make([]schemas.CakeOutputSchema, 0, len(cakes))
+	for _, cake := range cakes {
+		out = append(out, Cake(cake))
+	}
+	return out
+}
+
+// list converts a stored list to the output schema, where lists are never
+// null.
+func list[T any](values models.List[T]) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
+
+// document converts a stored JSON document to the output schema, where
+// empty documents are null.
+func document(doc models.JSON) json.RawMessage {
+	if len(doc) == 0 {
+		return nil
+	}
+	return json.RawMessage(doc)
+}
+
+// nutrition converts the stored nutrition facts to the output schema.
+func nutrition(facts *models.NutritionFacts) *schemas.NutritionSchema {
+	if facts == nil {
+		return nil
+	}
+	out := schemas.NutritionSchema(*facts)
+	return &out
+}
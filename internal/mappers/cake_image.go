@@ -0,0 +1,34 @@
+package mappers
+
+import (
+	"fmt"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+)
+
+// CakeImage converts the cake image model to its output schema, linking to
+// the routes serving the image and its thumbnail.
+func CakeImage(image models.CakeImage) schemas.CakeImageOutputSchema {
+	url := fmt.Sprintf("/cakes/%d/images/%d", image.CakeID, image.ID)
+	return schemas.CakeImageOutputSchema{
+		ID:           image.ID,
+		CakeID:       image.CakeID,
+		URL:          url,
+		ThumbnailURL: url + "/thumbnail",
+		ContentType:  image.ContentType,
+		Width:        image.Width,
+		Height:       image.Height,
+		Size:         image.Size,
+		CreatedAt:    timestamp(image.CreatedAt),
+	}
+}
+
+// CakeImages converts the cake image models to their output schema.
+func CakeImages(images []models.CakeImage) []schemas.CakeImageOutputSchema {
+	out := make([]schemas.CakeImageOutputSchema, 0, len(images))
+	for _, image := range images {
+		out = append(out, CakeImage(image))
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package mappers
+
+import (
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+)
+
+// CakeOption converts the cake option model to its output schema.
+func CakeOption(option models.CakeOption) schemas.CakeOptionOutputSchema {
+	return schemas.CakeOptionOutputSchema{
+		ID:         option.ID,
+		CakeID:     option.CakeID,
+		Kind:       option.Kind,
+		Name:       option.Name,
+		SKU:        option.SKU,
+		Price:      option.Price,
+		PriceDelta: option.PriceDelta,
+	}
+}
+
+// CakeOptions converts the cake option models to their output schema.
+func CakeOptions(options []models.CakeOption) []schemas.CakeOptionOutputSchema {
+	out := make([]schemas.CakeOptionOutputSchema, 0, len(options))
+	for _, option := range options {
+		out = append(out, CakeOption(option))
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package mappers
+
+import (
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+)
+
+// Customer converts the customer model and its stats to the output schema.
+// The stats are nil for the customers embedded in other resources, and the
+// orders and addresses are only set when they were preloaded.
+func Customer(customer models.Customer, stats *schemas.CustomerStats) schemas.CustomerOutputSchema {
+	out := schemas.CustomerOutputSchema{
+		ID:     customer.ID,
+		Fname:  customer.Fname,
+		Lname:  customer.Lname,
+		Email:  customer.Email,
+		Phone:  customer.Phone,
+		CPF:    cpf(customer.CPF),
+		Notes:  customer.Notes,
+		Tags:   list(customer.Tags),
+		Diet:   list(customer.Diet),
+		Active: customer.Active,
+	}
+	out.Allergies = make([]schemas.AllergySchema, 0, len(customer.Allergies))
+	for _, allergy := range customer.Allergies {
+		out.Allergies = append(out.Allergies, schemas.AllergySchema{Allergen: allergy.Allergen, Severe: allergy.Severe})
+	}
+	if stats != nil {
+		out.CustomerStats = &schemas.CustomerStats{
+			OrderCount:  stats.OrderCount,
+			TotalSpent:  stats.TotalSpent,
+			LastOrderAt: optionalTimestamp(stats.LastOrderAt),
+		}
+	}
+	if customer.Orders != nil {
+		out.Orders = Orders(customer.Orders)
+	}
+	if customer.Addresses != nil {
+		out.Addresses = Addresses(customer.Addresses)
+	}
+	return out
+}
+
+// cpf formats the digits of a CPF as 123.456.789-09.
+func cpf(digits *string) *string {
+	if digits == nil || len(*digits) != 11 {
+		return digits
+	}
+	formatted := (*digits)[:3] + "." + (*digits)[3:6] + "." + (*digits)[6:9] + "-" + (*digits)[9:]
+	return &formatted
+}
@@ -0,0 +1,20 @@
+// Package mappers converts the database models to the output schemas
+// returned by the API, so the models never reach the wire.
+package mappers
+
+import "time"
+
+// timestamp normalizes the times returned by the API to UTC, encoded as
+// RFC 3339 timestamps.
+func timestamp(t time.Time) time.Time {
+	return t.UTC()
+}
+
+// optionalTimestamp is timestamp for the times that may be unset.
+func optionalTimestamp(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := timestamp(*t)
+	return &utc
+}
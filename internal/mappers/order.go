@@ -0,0 +1,61 @@
+package mappers
+
+import (
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+)
+
+// Order converts the order model to its output schema, embedding the
+// customer, cake and address when they were preloaded.
+func Order(order models.Order) schemas.OrderOutputSchema {
+	out := schemas.OrderOutputSchema{
+		ID:               order.ID,
+		CustomerID:       order.CustomerID,
+		CakeID:           order.CakeID,
+		Qtd:              order.Qtd,
+		Delivered:        order.Delivered,
+		Notes:            order.Notes,
+		Pickup:           order.Pickup,
+		AddressID:        order.AddressID,
+		DueDate:          order.DueDate,
+		OptionIDs:        list(order.OptionIDs),
+		SKU:              order.SKU,
+		Personalization:  document(order.Personalization),
+		UnitPrice:        order.UnitPrice,
+		DeliveryFee:      order.DeliveryFee,
+		Total:            order.Total,
+		AllergenWarnings: list(order.AllergenWarnings),
+		CreatedAt:        timestamp(order.CreatedAt),
+		UpdatedAt:        timestamp(order.UpdatedAt),
+	}
+	if order.Customer.ID != 0 {
+		customer := Customer(order.Customer, nil)
+		out.Customer = &customer
+	}
+	if order.Cake.ID != 0 {
+		cake := Cake(order.Cake)
+		out.Cake = &cake
+	}
+	if order.Address != nil {
+		address := Address(*order.Address)
+		out.Address = &address
+	}
+	return out
+}
+
+// OrderDetails converts the order model to its output schema like Order,
+// listing the allergens of its cake.
+func OrderDetails(order models.Order, cake models.Cake) schemas.OrderOutputSchema {
+	out := Order(order)
+	out.Allergens = list(cake.Allergens)
+	return out
+}
+
+// Orders converts the order models to their output schema.
+func Orders(orders []models.Order) []schemas.OrderOutputSchema {
+	out := make([]schemas.OrderOutputSchema, 0, len(orders))
+	for _, order := range orders {
+		out = append(out, Order(order))
+	}
+	return out
+}
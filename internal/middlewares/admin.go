@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+)
+
+// RequireAdmin restricts the handler to requests sent with the admin token,
+// set on the ADMIN_TOKEN environment variable, as a bearer token on the
+// Authorization header. When the variable is not set the handler is disabled
+// and every request is forbidden.
+func RequireAdmin(next http.Handler) http.Handler {
+	adminToken := os.Getenv("ADMIN_TOKEN")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" {
+			errorhandling.ProblemResponse(w, http.StatusForbidden, "Admin endpoints are disabled")
+			return
+		}
+
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			errorhandling.ProblemResponse(w, http.StatusUnauthorized, "Admin credentials are required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
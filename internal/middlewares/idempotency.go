@@ -0,0 +1,167 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKeyHeader is the header identifying retries of the same request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// defaultIdempotencyTTL is used when IDEMPOTENCY_TTL is not set
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotentBodySize is the largest body read to be hashed, which is far
+// above the size of the JSON documents creating a resource
+const maxIdempotentBodySize = 1 << 20
+
+// Idempotency replays the stored responses of POST requests retried with
+// the same Idempotency-Key header, so a retry never creates a resource twice.
+// It wraps the handlers of the routes creating customers, cakes and orders.
+type Idempotency struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewIdempotency initializes the Idempotency middleware. Responses are kept
+// for the duration set on the IDEMPOTENCY_TTL environment variable, such as
+// "24h" or "30m", defaulting to 24 hours.
+func NewIdempotency(db *gorm.DB) *Idempotency {
+	ttl := defaultIdempotencyTTL
+	if value := os.Getenv("IDEMPOTENCY_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			log.Fatal("Invalid IDEMPOTENCY_TTL: ", value)
+		}
+		ttl = parsed
+	}
+	return &Idempotency{db: db, ttl: ttl}
+}
+
+// Handler processes the first POST request sent with a given key and stores
+// its response. Retries with the same key and payload get the stored
+// response replayed, while reusing the key with a different payload is
+// rejected with 422 Unprocessable Entity, and bodies over 1 MB with 413
+// Content Too Large. Requests without the header and other methods are passed
+// through.
+func (m *Idempotency) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > 255 {
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid idempotency key")
+			return
+		}
+
+		var maxBytesErr *http.MaxBytesError
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+		switch {
+		case errors.As(err, &maxBytesErr):
+			errorhandling.ProblemResponse(w, http.StatusRequestEntityTooLarge, "The request body exceeds 1 MB")
+			return
+		case err != nil:
+			errorhandling.ProblemResponse(w, http.StatusBadRequest, "Invalid input")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(body)
+		record := models.IdempotencyKey{
+			Key:         key,
+			Route:       r.Method + " " + r.URL.Path,
+			RequestHash: hex.EncodeToString(hash[:]),
+			ExpiresAt:   time.Now().Add(m.ttl),
+		}
+
+		m.db.Where("expires_at <= ?", time.Now()).Delete(&models.IdempotencyKey{})
+
+		// the primary key makes concurrent requests with the same key race
+		// for the insert, so only one of them is processed
+		created := m.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if !errorhandling.CheckOrHttpError(created.Error, w, http.StatusInternalServerError, "Internal server error") {
+			return
+		}
+
+		if created.RowsAffected == 0 {
+			m.replay(w, record)
+			return
+		}
+
+		// a panicking handler leaves no response to replay, so the key is
+		// released instead of blocking the retries until it expires
+		defer func() {
+			if p := recover(); p != nil {
+				m.db.Delete(&record)
+				panic(p)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		// server errors are not stored so the request can be retried
+		if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+			m.db.Delete(&record)
+			return
+		}
+
+		m.db.Model(&record).Updates(map[string]any{
+			"Status":          rec.status,
+			"ContentType":     rec.Header().Get("Content-Type"),
+			"ContentLanguage": rec.Header().Get("Content-Language"),
+			"ETag":            rec.Header().Get("ETag"),
+			"Body":            rec.body.Bytes(),
+		})
+	})
+}
+
+// replay writes the response stored for the key of the given request.
+func (m *Idempotency) replay(w http.ResponseWriter, request models.IdempotencyKey) {
+	var stored models.IdempotencyKey
+	found := m.db.First(&stored, "key = ? AND route = ?", request.Key, request.Route)
+	if !errorhandling.CheckOrHttpError(found.Error, w, http.StatusInternalServerError, "Internal server error") {
+		return
+	}
+
+	switch {
+	case stored.RequestHash != request.RequestHash:
+		errorhandling.ProblemResponse(
+			w, http.StatusUnprocessableEntity, "Idempotency key was already used with a different payload",
+		)
+
+	case stored.Status == 0:
+		errorhandling.ProblemResponse(
+			w, http.StatusConflict, "A request with this idempotency key is still being processed",
+		)
+
+	default:
+		for header, value := range map[string]string{
+			"Content-Type":     stored.ContentType,
+			"Content-Language": stored.ContentLanguage,
+			"ETag":             stored.ETag,
+		} {
+			if value != "" {
+				w.Header().Set(header, value)
+			}
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(stored.Status)
+		w.Write(stored.Body)
+	}
+}
@@ -0,0 +1,18 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/i18n"
+)
+
+// Language negotiates the language of the response from the Accept-Language
+// header and announces it on the Content-Language header, which is read
+// back when the error messages are translated.
+func Language(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", i18n.Negotiate(r.Header.Get("Accept-Language")))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+)
+
+// RequestID assigns an identifier to every request, reusing the one sent by
+// the client in the X-Request-ID header when present. The identifier is
+// echoed back on the response so error bodies can reference it.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(httphelpers.RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+
+		w.Header().Set(httphelpers.RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,25 @@
+package models
+
+import "gorm.io/gorm"
+
+// Address is a delivery address of a customer. Addresses are soft deleted,
+// so the orders delivered to them keep their reference.
+type Address struct {
+	ID         uint
+	CustomerID uint   `gorm:"not null;index"`
+	Label      string `gorm:"size:50;not null;default:''"`
+	Street     string `gorm:"size:255;not null"`
+	Number     string `gorm:"size:20;not null"`
+	Complement string `gorm:"size:100;not null;default:''"`
+	// Neighborhood and CEP pick the delivery zone of the address
+	Neighborhood string `gorm:"size:100;not null"`
+	City         string `gorm:"size:100;not null"`
+	// CEP holds the 8 digits of the postal code, without the hyphen
+	CEP string `gorm:"size:8;not null"`
+	// Default marks the address orders are delivered to when they name none.
+	// A customer has a single default address.
+	Default   bool           `gorm:"column:is_default;not null;default:false"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	// Version is incremented on every update, for optimistic concurrency
+	Version uint `gorm:"not null;default:1"`
+}
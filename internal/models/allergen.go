@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Allergens are the allergens tracked on the cakes and on the allergies of
+// the customers, following the ones Brazilian food labels must declare.
+var Allergens = []string{
+	"gluten", "lactose", "milk", "eggs", "peanuts", "nuts", "soy", "sesame", "fish", "crustaceans",
+}
+
+// Diets are the dietary restrictions recorded for the customers.
+var Diets = []string{"vegetarian", "vegan", "sugar_free", "gluten_free", "lactose_free"}
+
+// Allergy is an allergen a customer must avoid.
+type Allergy struct {
+	Allergen string `json:"allergen"`
+	// Severe allergies block the orders of cakes holding the allergen, while
+	// the other ones only warn about it
+	Severe bool `json:"severe"`
+}
+
+// NutritionFacts are the nutrition facts of a serving of a cake, as printed
+// on Brazilian food labels. They are stored as a JSON object on a text
+// column.
+type NutritionFacts struct {
+	// ServingSize is in grams
+	ServingSize float64 `json:"servingSize"`
+	// Energy is in kcal, Sodium in milligrams and the other facts in grams
+	Energy        float64 `json:"energy"`
+	Carbohydrates float64 `json:"carbohydrates"`
+	Sugars        float64 `json:"sugars"`
+	Proteins      float64 `json:"proteins"`
+	TotalFat      float64 `json:"totalFat"`
+	SaturatedFat  float64 `json:"saturatedFat"`
+	TransFat      float64 `json:"transFat"`
+	Fiber         float64 `json:"fiber"`
+	Sodium        float64 `json:"sodium"`
+}
+
+// GormDataType stores the nutrition facts on text columns.
+func (NutritionFacts) GormDataType() string {
+	return "text"
+}
+
+// Value encodes the nutrition facts as a JSON object.
+func (n NutritionFacts) Value() (driver.Value, error) {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan decodes the nutrition facts stored as a JSON object.
+func (n *NutritionFacts) Scan(value any) error {
+	switch v := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), n)
+	case []byte:
+		return json.Unmarshal(v, n)
+	default:
+		return fmt.Errorf("models: cannot scan %T into nutrition facts", value)
+	}
+}
@@ -1,8 +1,45 @@
 package models
 
+import "time"
+
+// Weekdays lists the days of the week the cakes can be offered on, in the
+// order of time.Weekday
+var Weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
 // Cake stores data about a cake
 type Cake struct {
-	ID    uint  
+	ID    uint
 	Name  string `gorm:"unique;not null;size:100"`
-	Price uint64  `gorm:"not null;default:0"`
+	Price uint64 `gorm:"not null;default:0"`
+	// Allergens held by the cake, among Allergens
+	Allergens List[string] `gorm:"not null;default:'[]'"`
+	// Nutrition is nil for the cakes without nutrition facts
+	Nutrition *NutritionFacts
+	// PersonalizationSchema is the JSON Schema of the personalization of the
+	// orders of the cake, such as its message and theme. Cakes without one
+	// take no personalization.
+	PersonalizationSchema JSON
+	// Category groups the cakes of the catalog, such as "christmas", and Tags
+	// describe them, both in lower case
+	Category string       `gorm:"size:30;not null;default:'';index"`
+	Tags     List[string] `gorm:"not null;default:'[]'"`
+	// Available is turned off to take the cake out of the catalog for a
+	// while. It is a pointer so gorm does not replace false with the default.
+	Available *bool `gorm:"not null;default:true"`
+	// AvailableFrom and AvailableUntil limit the dates the cake is ordered
+	// for, both included, and Weekdays the days of the week, among Weekdays.
+	// The dates are stored as YYYY-MM-DD, so they are compared as text. Nil
+	// dates and empty weekdays leave the availability unlimited.
+	AvailableFrom  *string      `gorm:"size:10"`
+	AvailableUntil *string      `gorm:"size:10"`
+	Weekdays       List[string] `gorm:"not null;default:'[]'"`
+	// ArchivedAt hides the cake from the catalog while keeping it for the
+	// orders that reference it
+	ArchivedAt *time.Time `gorm:"index"`
+	// Version is incremented on every update, for optimistic concurrency
+	Version uint `gorm:"not null;default:1"`
+	// Options are the variants the cake is ordered in, such as its sizes
+	Options []CakeOption `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// Images are the photos of the cake, shown on the storefront
+	Images []CakeImage `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// CakeImage stores data about a photo of a cake. The uploaded image and its
+// thumbnail are kept on the storage under Key and ThumbnailKey, which are
+// never reused, so the files served for an image never change.
+type CakeImage struct {
+	ID           uint
+	CakeID       uint   `gorm:"not null;index"`
+	Key          string `gorm:"size:100;not null;uniqueIndex"`
+	ContentType  string `gorm:"size:30;not null"`
+	Width        uint   `gorm:"not null"`
+	Height       uint   `gorm:"not null"`
+	Size         int64  `gorm:"not null"`
+	ThumbnailKey string `gorm:"size:100;not null"`
+	// ThumbnailType is image/jpeg for JPEG images and image/png otherwise
+	ThumbnailType string `gorm:"size:30;not null"`
+	CreatedAt     time.Time
+}
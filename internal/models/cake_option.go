@@ -0,0 +1,36 @@
+package models
+
+import "gorm.io/gorm"
+
+// Kinds of the cake options. An order picks one option of each kind offered
+// by its cake.
+const (
+	OptionSize     = "size"
+	OptionFlavor   = "flavor"
+	OptionFilling  = "filling"
+	OptionFrosting = "frosting"
+)
+
+// OptionKinds lists the kinds of the cake options, in the order their SKUs
+// are joined on the SKU of an order.
+var OptionKinds = []string{OptionSize, OptionFlavor, OptionFilling, OptionFrosting}
+
+// CakeOption is a variant option of a cake, such as a size or a flavor.
+// Options are soft deleted, so the orders placed with them keep their
+// reference.
+type CakeOption struct {
+	ID     uint
+	CakeID uint   `gorm:"not null;index"`
+	Kind   string `gorm:"size:20;not null"`
+	Name   string `gorm:"size:100;not null"`
+	// SKU is unique among every option, including the deleted ones, so the
+	// SKUs of the orders keep naming a single combination
+	SKU string `gorm:"size:30;not null;uniqueIndex"`
+	// Price replaces the price of the cake when set, which only the size
+	// options do. PriceDelta, in cents and possibly negative, is added to it.
+	Price      *uint64
+	PriceDelta int64          `gorm:"not null;default:0"`
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	// Version is incremented on every update, for optimistic concurrency
+	Version uint `gorm:"not null;default:1"`
+}
@@ -1,10 +1,29 @@
 package models
 
 type Customer struct {
-	ID       uint
-	Fname    string `gorm:"size:100;not null"`
-	Lname    string `gorm:"size:255;not null"`
-	Email    string `gorm:"unique;not null;size:345"`
-	Active   bool   `gorm:"default:true"`
-	Orders []Order  `gorm:"many2many:orders;"`
+	ID    uint
+	Fname string `gorm:"size:100;not null"`
+	Lname string `gorm:"size:255;not null"`
+	// Email, Phone and CPF are nil when unknown, and a customer has at least
+	// an email or a phone
+	Email *string `gorm:"unique;size:345"`
+	// Phone is stored in the E.164 format, such as +5511912345678
+	Phone *string `gorm:"size:16;index"`
+	// CPF holds the 11 digits of the document, without punctuation
+	CPF    *string `gorm:"unique;size:11"`
+	Active bool    `gorm:"default:true"`
+	// Notes are free text written by grandma about the customer
+	Notes string `gorm:"size:1000;not null;default:''"`
+	// Tags group the customers, such as "vip", "wholesale" or "family", and
+	// are stored in lower case
+	Tags      List[string]  `gorm:"not null;default:'[]'"`
+	Allergies List[Allergy] `gorm:"not null;default:'[]'"`
+	// Diet lists the dietary restrictions of the customer, among Diets
+	Diet List[string] `gorm:"not null;default:'[]'"`
+	// Version is incremented on every update, for optimistic concurrency
+	Version uint `gorm:"not null;default:1"`
+	// Orders placed by the customer, referencing it through Order.CustomerID
+	Orders []Order `gorm:"constraint:OnUpdate:CASCADE"`
+	// Addresses the orders of the customer are delivered to
+	Addresses []Address `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
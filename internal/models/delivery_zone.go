@@ -0,0 +1,20 @@
+package models
+
+// DeliveryZone is an area covered by the deliveries, defined by a range of
+// CEPs or by a neighborhood, and the fee charged to deliver there.
+type DeliveryZone struct {
+	ID   uint
+	Name string `gorm:"unique;not null;size:100"`
+	// CEPStart and CEPEnd bound the range of CEPs of the zone, both included.
+	// They are empty for the zones defined by neighborhood.
+	CEPStart string `gorm:"size:8;not null;default:''"`
+	CEPEnd   string `gorm:"size:8;not null;default:''"`
+	// Neighborhood and City define the zones matched by neighborhood. City
+	// may be empty to match the neighborhood in any city.
+	Neighborhood string `gorm:"size:100;not null;default:''"`
+	City         string `gorm:"size:100;not null;default:''"`
+	// Fee is the delivery fee in cents
+	Fee uint64 `gorm:"not null;default:0"`
+	// Version is incremented on every update, for optimistic concurrency
+	Version uint `gorm:"not null;default:1"`
+}
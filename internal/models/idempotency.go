@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the response of a request sent with an
+// Idempotency-Key header, so retries of the same request are replayed
+// instead of being processed again.
+type IdempotencyKey struct {
+	Key         string `gorm:"primaryKey;size:255"`
+	Route       string `gorm:"primaryKey;size:255"`
+	RequestHash string `gorm:"size:64;not null"`
+	// Status is zero while the first request is still being processed
+	Status int
+	// ContentType, ContentLanguage and ETag are the headers of the response
+	// replayed along with its body
+	ContentType     string `gorm:"size:255"`
+	ContentLanguage string `gorm:"size:35"`
+	ETag            string `gorm:"size:100"`
+	Body            []byte
+	CreatedAt       time.Time
+	ExpiresAt       time.Time `gorm:"index;not null"`
+}
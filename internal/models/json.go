@@ -0,0 +1,48 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON is a JSON document stored as is on a text column, for the documents
+// whose shape is defined by the API users, such as the personalization of
+// an order. Empty documents are stored as NULL.
+type JSON json.RawMessage
+
+// GormDataType stores the documents on text columns.
+func (JSON) GormDataType() string {
+	return "text"
+}
+
+// Value returns the document, or NULL for empty ones.
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+// MarshalJSON returns the document, or null for empty ones.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// Scan reads a stored document. NULL values are scanned as empty documents.
+func (j *JSON) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case string:
+		*j = JSON(v)
+	case []byte:
+		*j = JSON(append([]byte(nil), v...))
+	default:
+		return fmt.Errorf("models: cannot scan %T into a JSON document", value)
+	}
+	return nil
+}
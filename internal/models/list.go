@@ -0,0 +1,47 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// List is a list of values stored as a JSON array on a text column, for the
+// short lists that are read along with their record, such as the tags of a
+// customer. Nil lists are stored as empty arrays.
+type List[T any] []T
+
+// GormDataType stores the lists on text columns.
+func (List[T]) GormDataType() string {
+	return "text"
+}
+
+// Value encodes the list as a JSON array.
+func (l List[T]) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]T(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan decodes a list stored as a JSON array. NULL values are scanned as nil
+// lists.
+func (l *List[T]) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("models: cannot scan %T into a list", value)
+	}
+	return json.Unmarshal(data, (*[]T)(l))
+}
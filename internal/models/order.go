@@ -7,8 +7,40 @@ import (
 // Order represents the schema of an order made by a customer
 type Order struct {
 	gorm.Model
-	CustomerID uint  `gorm:"primaryKey"`
-	CakeID     uint  `gorm:"primaryKey"`
-	Qtd        uint  
-	Delivered  bool  `gorm:"default:false"`
+	CustomerID uint `gorm:"not null;index"`
+	CakeID     uint `gorm:"not null;index"`
+	Qtd        uint
+	Delivered  bool `gorm:"default:false"`
+	// Notes are free text written by grandma, such as the cake message
+	Notes string `gorm:"size:1000;not null;default:''"`
+	// Pickup orders are collected at the shop. The other ones are delivered
+	// to AddressID, for the fee of its delivery zone.
+	Pickup    bool  `gorm:"not null;default:false"`
+	AddressID *uint `gorm:"index"`
+	// DueDate is the YYYY-MM-DD date the order is picked up or delivered on,
+	// which the cake must be available on
+	DueDate *string `gorm:"size:10;index"`
+	// OptionIDs are the options of the cake the order was placed with, one
+	// of each kind offered by the cake, and SKU joins their SKUs
+	OptionIDs List[uint] `gorm:"not null;default:'[]'"`
+	SKU       string     `gorm:"size:130;not null;default:''"`
+	// Personalization holds the data asked by the personalization schema of
+	// the cake, checked when the order is placed and when its cake or
+	// personalization change
+	Personalization JSON
+	// UnitPrice, DeliveryFee and Total are in cents, computed when the order
+	// is placed and when its cake, options, quantity or delivery change
+	UnitPrice   uint64 `gorm:"not null;default:0"`
+	DeliveryFee uint64 `gorm:"not null;default:0"`
+	Total       uint64 `gorm:"not null;default:0"`
+	// AllergenWarnings lists the allergens of the cake the customer has mild
+	// allergies to, checked when the order is placed and when its customer
+	// or cake change
+	AllergenWarnings List[string] `gorm:"not null;default:'[]'"`
+	// Version is incremented on every update, for optimistic concurrency
+	Version uint `gorm:"not null;default:1"`
+
+	Customer Customer `json:"-" gorm:"constraint:OnUpdate:CASCADE"`
+	Cake     Cake     `json:"-" gorm:"constraint:OnUpdate:CASCADE"`
+	Address  *Address `json:"-" gorm:"constraint:OnUpdate:CASCADE"`
 }
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// SchemaMigration records a data migration applied to the database, so it is
+// run only once on each database.
+type SchemaMigration struct {
+	Version   string `gorm:"primaryKey;size:100"`
+	AppliedAt time.Time
+}
@@ -0,0 +1,202 @@
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// jsonPatchSchema describes a JSON Patch document (RFC 6902)
+var jsonPatchSchema = &Schema{
+	Type: "array",
+	Items: &Schema{
+		Type:     "object",
+		Required: []string{"op", "path"},
+		Properties: map[string]*Schema{
+			"op":    {Type: "string", Enum: []any{"add", "remove", "replace", "move", "copy", "test"}},
+			"path":  {Type: "string"},
+			"from":  {Type: "string"},
+			"value": {},
+		},
+	},
+}
+
+// fileSchema describes the files sent or returned by the operations
+var fileSchema = &Schema{Type: "string", Format: "binary"}
+
+// adminSecurityScheme names the security scheme of the admin routes
+const adminSecurityScheme = "adminToken"
+
+// Route documents an operation registered on the router. Request and
+// Response hold a zero value of the body types, which are reflected into
+// schemas when the document is built. RequestFiles and ResponseFiles list the
+// media types of the files taken or returned instead of, or besides, JSON. The files are
+// taken as the body or on the "file" field of a multipart body, unless FormFiles names the
+// multipart field taking one or more of them. Path parameters not listed in Params are
+// documented as integer ids. Admin routes require the admin bearer token.
+type Route struct {
+	Method        string
+	Path          string
+	Summary       string
+	Tag           string
+	Params        []Parameter
+	Request       any
+	RequestFiles  []string
+	FormFiles     string
+	Response      any
+	ResponseFiles []string
+	Status        int
+	Admin         bool
+}
+
+// Build generates the OpenAPI document of the given routes. The routes are
+// kept in sync with the router by the tests of the routes package.
+func Build(info Info, routes []Route, errorResponse any) *Document {
+	reg := newSchemaRegistry()
+	errorSchema := reg.schemaOf(errorResponse)
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+	}
+	for _, route := range routes {
+		item, ok := doc.Paths[route.Path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = buildOperation(reg, route, errorSchema)
+
+		if route.Admin {
+			doc.Components.SecuritySchemes = map[string]*SecurityScheme{
+				adminSecurityScheme: {Type: "http", Scheme: "bearer"},
+			}
+		}
+	}
+	doc.Components.Schemas = reg.schemas
+
+	return doc
+}
+
+func buildOperation(reg *schemaRegistry, route Route, errorSchema *Schema) *Operation {
+	op := &Operation{
+		OperationID: operationID(route),
+		Summary:     route.Summary,
+		Responses:   make(map[string]Response),
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	if route.Admin {
+		op.Security = []map[string][]string{{adminSecurityScheme: {}}}
+	}
+
+	declared := make(map[string]bool, len(route.Params))
+	for _, param := range route.Params {
+		declared[param.In+":"+param.Name] = true
+	}
+
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		if declared["path:"+match[1]] {
+			continue
+		}
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "integer", Format: "int32"},
+		})
+	}
+	op.Parameters = append(op.Parameters, route.Params...)
+
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				"application/json": {Schema: reg.schemaOf(route.Request)},
+			},
+		}
+
+		// PATCH endpoints take merge patches and JSON Patch documents
+		if route.Method == http.MethodPatch {
+			op.RequestBody.Content["application/merge-patch+json"] = op.RequestBody.Content["application/json"]
+			op.RequestBody.Content["application/json-patch+json"] = MediaType{Schema: jsonPatchSchema}
+		}
+	}
+
+	if route.FormFiles != "" {
+		one := 1
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+			"multipart/form-data": {
+				Schema: &Schema{
+					Type:       "object",
+					Required:   []string{route.FormFiles},
+					Properties: map[string]*Schema{route.FormFiles: {Type: "array", Items: fileSchema, MinItems: &one}},
+				},
+				Encoding: map[string]Encoding{route.FormFiles: {ContentType: strings.Join(route.RequestFiles, ", ")}},
+			},
+		}}
+	} else if len(route.RequestFiles) > 0 {
+		op.RequestBody = &RequestBody{Required: true, Content: make(map[string]MediaType)}
+		for _, mediaType := range route.RequestFiles {
+			op.RequestBody.Content[mediaType] = MediaType{Schema: fileSchema}
+		}
+		op.RequestBody.Content["multipart/form-data"] = MediaType{Schema: &Schema{
+			Type:       "object",
+			Required:   []string{"file"},
+			Properties: map[string]*Schema{"file": fileSchema},
+		}}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := Response{Description: http.StatusText(status)}
+	if route.Response != nil {
+		success.Content = map[string]MediaType{
+			"application/json": {Schema: reg.schemaOf(route.Response)},
+		}
+	}
+	for _, mediaType := range route.ResponseFiles {
+		if success.Content == nil {
+			success.Content = make(map[string]MediaType)
+		}
+		success.Content[mediaType] = MediaType{Schema: fileSchema}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	if errorSchema != nil {
+		op.Responses["default"] = Response{
+			Description: "Error",
+			Content: map[string]MediaType{
+				"application/problem+json": {Schema: errorSchema},
+			},
+		}
+	}
+
+	return op
+}
+
+// operationID derives an identifier such as "getCakesId" from the route.
+func operationID(route Route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+
+	for segment := range strings.SplitSeq(route.Path, "/") {
+		segment = strings.Trim(segment, "{}")
+		if segment == "" {
+			continue
+		}
+		if name, _, found := strings.Cut(segment, ":"); found {
+			segment = name
+		}
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,252 @@
+package openapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	nullTimeType = reflect.TypeOf(sql.NullTime{})
+	rawJSONType  = reflect.TypeOf(json.RawMessage{})
+	patchType    = reflect.TypeOf((*schemas.PatchField)(nil)).Elem()
+)
+
+// schemaRegistry converts Go types into OpenAPI schemas, storing every named
+// struct as a reusable component so it can be referenced by the operations.
+type schemaRegistry struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: make(map[string]*Schema)}
+}
+
+// schemaOf returns the schema of the given value. Named structs are
+// registered as components and returned as references.
+func (reg *schemaRegistry) schemaOf(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	return reg.schemaFor(reflect.TypeOf(v))
+}
+
+func (reg *schemaRegistry) schemaFor(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		s := reg.schemaFor(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	// raw JSON documents take any value, such as the personalization of the
+	// orders, whose shape is defined by the API users
+	if t == rawJSONType {
+		return &Schema{}
+	}
+
+	// fields of the patch documents are encoded as their value or null
+	if t.Kind() == reflect.Struct && t.Implements(patchType) {
+		if value, ok := t.FieldByName("Value"); ok {
+			s := reg.schemaFor(value.Type)
+			s.Nullable = s.Ref == ""
+			return s
+		}
+	}
+
+	// sql.NullTime and its named variants, such as gorm.DeletedAt
+	if t.Kind() == reflect.Struct && t.ConvertibleTo(nullTimeType) {
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		format := "int32"
+		if t.Kind() == reflect.Uint64 {
+			format = "int64"
+		}
+		return &Schema{Type: "integer", Format: format, Minimum: &zero}
+
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+
+	case reflect.String:
+		return &Schema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: reg.schemaFor(t.Elem())}
+
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: reg.schemaFor(t.Elem())}
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return reg.structSchema(t)
+		}
+		if _, ok := reg.schemas[t.Name()]; !ok {
+			// reserve the name first so recursive types terminate
+			reg.schemas[t.Name()] = &Schema{}
+			*reg.schemas[t.Name()] = *reg.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+
+	default:
+		return &Schema{}
+	}
+}
+
+// structSchema builds an object schema from the exported fields of a struct,
+// using the json tags for the property names and the validate tags for the
+// constraints.
+func (reg *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embeddedType := field.Type
+			if embeddedType.Kind() == reflect.Pointer {
+				embeddedType = embeddedType.Elem()
+			}
+			embedded := reg.structSchema(embeddedType)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+
+		prop := reg.schemaFor(field.Type)
+		if applyValidateTag(prop, field.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+	return s
+}
+
+// jsonName returns the name used by encoding/json for the field and
+// whether the field is ignored by it.
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, false
+}
+
+// applyValidateTag translates the go-playground/validator rules into schema
+// constraints and reports whether the field is required. Rules after a dive
+// apply to the elements and are ignored, and fields that may be omitted are
+// never required.
+func applyValidateTag(s *Schema, tag string) bool {
+	if tag == "" || tag == "-" {
+		return false
+	}
+
+	required, omittable := false, false
+	for rule := range strings.SplitSeq(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "dive":
+			return required && !omittable
+
+		case "omitempty", "omitnil", "omitzero":
+			omittable = true
+
+		case "required":
+			required = true
+
+		case "email":
+			s.Format = "email"
+
+		case "url", "uri":
+			s.Format = "uri"
+
+		case "min", "gte":
+			setLowerBound(s, param)
+
+		case "max", "lte":
+			setUpperBound(s, param)
+
+		case "oneof":
+			for value := range strings.FieldsSeq(param) {
+				s.Enum = append(s.Enum, value)
+			}
+		}
+	}
+	return required && !omittable
+}
+
+func setLowerBound(s *Schema, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch s.Type {
+	case "string":
+		length := int(n)
+		s.MinLength = &length
+	case "array":
+		length := int(n)
+		s.MinItems = &length
+	default:
+		s.Minimum = &n
+	}
+}
+
+func setUpperBound(s *Schema, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch s.Type {
+	case "string":
+		length := int(n)
+		s.MaxLength = &length
+	case "array":
+		length := int(n)
+		s.MaxItems = &length
+	default:
+		s.Maximum = &n
+	}
+}
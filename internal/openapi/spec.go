@@ -0,0 +1,98 @@
+package openapi
+
+// Document is the root object of an OpenAPI 3 document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info provides metadata about the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem describes the operations available on a single path, keyed by
+// the lower case HTTP method.
+type PathItem map[string]*Operation
+
+// Operation describes a single API operation on a path.
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter describes a path or query parameter of an operation.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the body accepted by an operation.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a single response of an operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a request or response body, and the media
+// types of the fields of multipart bodies.
+type MediaType struct {
+	Schema   *Schema             `json:"schema"`
+	Encoding map[string]Encoding `json:"encoding,omitempty"`
+}
+
+// Encoding lists the comma separated media types taken by a field of a
+// multipart body.
+type Encoding struct {
+	ContentType string `json:"contentType"`
+}
+
+// Components holds the reusable schemas and security schemes referenced by
+// the operations.
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how the restricted operations are authenticated.
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// Schema is the subset of the JSON Schema dialect used by OpenAPI 3 that
+// the generator is able to produce from Go types.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
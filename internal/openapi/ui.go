@@ -0,0 +1,21 @@
+package openapi
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed ui/index.html
+var uiPage string
+
+var uiTemplate = template.Must(template.New("docs").Parse(uiPage))
+
+// UIHandler serves the embedded documentation page, which renders the
+// document served at specURL without loading any external asset.
+func UIHandler(specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		uiTemplate.Execute(w, specURL)
+	}
+}
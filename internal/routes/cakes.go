@@ -1,17 +1,35 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/LeandroDeJesus-S/confectionery/internal/controllers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/middlewares"
 	"github.com/gorilla/mux"
 )
 
-func SetupCakeRoutes(baseRouter *mux.Router, cakeController *controllers.CakeController) {
+func SetupCakeRoutes(
+	baseRouter *mux.Router, cakeController *controllers.CakeController, idempotency *middlewares.Idempotency,
+) {
 	r := baseRouter.PathPrefix("/cakes").Subrouter()
 
 	r.HandleFunc("/", cakeController.GetCakes).Methods("GET")
-	r.HandleFunc("/", cakeController.CreateCake).Methods("POST")
+	r.Handle("/", idempotency.Handler(http.HandlerFunc(cakeController.CreateCake))).Methods("POST")
+	r.HandleFunc("/bulk", cakeController.CreateCakes).Methods("POST")
 
 	r.HandleFunc("/{id}", cakeController.GetCake).Methods("GET")
 	r.HandleFunc("/{id}", cakeController.UpdateCake).Methods("PATCH")
 	r.HandleFunc("/{id}", cakeController.DeleteCake).Methods("DELETE")
+	r.HandleFunc("/{id}/restore", cakeController.RestoreCake).Methods("POST")
+
+	r.HandleFunc("/{id}/options", cakeController.GetCakeOptions).Methods("GET")
+	r.HandleFunc("/{id}/options", cakeController.CreateCakeOption).Methods("POST")
+	r.HandleFunc("/{id}/options/{optionId}", cakeController.UpdateCakeOption).Methods("PATCH")
+	r.HandleFunc("/{id}/options/{optionId}", cakeController.DeleteCakeOption).Methods("DELETE")
+
+	r.HandleFunc("/{id}/images", cakeController.GetCakeImages).Methods("GET")
+	r.HandleFunc("/{id}/images", cakeController.UploadCakeImages).Methods("POST")
+	r.HandleFunc("/{id}/images/{imageId}", cakeController.GetCakeImage).Methods("GET")
+	r.HandleFunc("/{id}/images/{imageId}", cakeController.DeleteCakeImage).Methods("DELETE")
+	r.HandleFunc("/{id}/images/{imageId}/thumbnail", cakeController.GetCakeImageThumbnail).Methods("GET")
 }
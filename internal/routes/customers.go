@@ -1,17 +1,34 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/LeandroDeJesus-S/confectionery/internal/controllers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/middlewares"
 	"github.com/gorilla/mux"
 )
 
-func SetupCustomersRoutes(baseRouter *mux.Router, customerController *controllers.CustomerController) {
+func SetupCustomersRoutes(
+	baseRouter *mux.Router, customerController *controllers.CustomerController, idempotency *middlewares.Idempotency,
+) {
 	customersRouter := baseRouter.PathPrefix("/customers").Subrouter()
 
 	customersRouter.HandleFunc("/", customerController.GetAllCustomers).Methods("GET")
-	customersRouter.HandleFunc("/", customerController.CreateCustomer).Methods("POST")
+	customersRouter.Handle("/", idempotency.Handler(http.HandlerFunc(customerController.CreateCustomer))).Methods("POST")
+	customersRouter.HandleFunc("/trash", customerController.GetCustomersTrash).Methods("GET")
+	customersRouter.HandleFunc("/bulk", customerController.DeleteCustomers).Methods("DELETE")
 
 	customersRouter.HandleFunc("/{id}", customerController.GetCustomer).Methods("GET")
 	customersRouter.HandleFunc("/{id}", customerController.UpdateCustomer).Methods("PATCH")
 	customersRouter.HandleFunc("/{id}", customerController.DeleteCustomer).Methods("DELETE")
+	customersRouter.HandleFunc("/{id}/orders", customerController.GetCustomerOrders).Methods("GET")
+	customersRouter.HandleFunc("/{id}/orders", customerController.CreateCustomerOrder).Methods("POST")
+	customersRouter.HandleFunc("/{id}/addresses", customerController.GetCustomerAddresses).Methods("GET")
+	customersRouter.HandleFunc("/{id}/addresses", customerController.CreateCustomerAddress).Methods("POST")
+	customersRouter.HandleFunc("/{id}/addresses/{addressId}", customerController.UpdateCustomerAddress).Methods("PATCH")
+	customersRouter.HandleFunc("/{id}/addresses/{addressId}", customerController.DeleteCustomerAddress).Methods("DELETE")
+	customersRouter.HandleFunc("/{id}/restore", customerController.RestoreCustomer).Methods("POST")
+	customersRouter.Handle(
+		"/{id}/purge", middlewares.RequireAdmin(http.HandlerFunc(customerController.PurgeCustomer)),
+	).Methods("DELETE")
 }
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/LeandroDeJesus-S/confectionery/internal/controllers"
+	"github.com/gorilla/mux"
+)
+
+func SetupDeliveryZoneRoutes(baseRouter *mux.Router, c *controllers.DeliveryZoneController) {
+	r := baseRouter.PathPrefix("/delivery-zones").Subrouter()
+
+	r.HandleFunc("/", c.GetDeliveryZones).Methods("GET")
+	r.HandleFunc("/", c.CreateDeliveryZone).Methods("POST")
+	r.HandleFunc("/{id}", c.UpdateDeliveryZone).Methods("PATCH")
+	r.HandleFunc("/{id}", c.DeleteDeliveryZone).Methods("DELETE")
+}
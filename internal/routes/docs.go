@@ -0,0 +1,211 @@
+package routes
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/controllers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/middlewares"
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"github.com/LeandroDeJesus-S/confectionery/internal/openapi"
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/spreadsheet"
+	"github.com/gorilla/mux"
+)
+
+// apiRoutes documents every route registered by the Setup*Routes functions.
+// TestAPIRoutesMatchRouter fails when this table and the router disagree.
+var apiRoutes = []openapi.Route{
+	{Method: "GET", Path: "/customers/", Tag: "customers", Summary: "List active customers", Params: []openapi.Parameter{fieldsParam, includeDeletedParam, customerTagsParam, includeParam("orders", "addresses")}, Response: []schemas.CustomerOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "POST", Path: "/customers/", Tag: "customers", Summary: "Create a customer", Params: []openapi.Parameter{idempotencyKeyParam}, Request: schemas.CustomerInputSchema{}, Response: schemas.CustomerOutputSchema{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/customers/trash", Tag: "customers", Summary: "List deleted customers", Params: []openapi.Parameter{fieldsParam, includeParam("orders", "addresses")}, Response: []schemas.CustomerOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "DELETE", Path: "/customers/bulk", Tag: "customers", Summary: "Deactivate many customers", Params: bulkParams, Request: []schemas.CustomerBulkDeleteInputSchema{}, Response: schemas.BulkOutputSchema{}},
+	{Method: "GET", Path: "/customers/{id}", Tag: "customers", Summary: "Get an active customer", Params: []openapi.Parameter{fieldsParam, includeParam("orders", "addresses")}, Response: schemas.CustomerOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "PATCH", Path: "/customers/{id}", Tag: "customers", Summary: "Update a customer", Request: schemas.CustomerPatchInputSchema{}, Response: schemas.CustomerOutputSchema{}},
+	{Method: "DELETE", Path: "/customers/{id}", Tag: "customers", Summary: "Deactivate a customer", Status: http.StatusNoContent},
+	{Method: "GET", Path: "/customers/{id}/orders", Tag: "customers", Summary: "List the orders of a customer", Params: append([]openapi.Parameter{fieldsParam, includeParam("customer", "cake", "address")}, orderFilterParams...), Response: []schemas.OrderOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "POST", Path: "/customers/{id}/orders", Tag: "customers", Summary: "Place an order for a customer", Request: schemas.CustomerOrderInputSchema{}, Response: schemas.OrderOutputSchema{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/customers/{id}/addresses", Tag: "customers", Summary: "List the addresses of a customer", Response: []schemas.AddressOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "POST", Path: "/customers/{id}/addresses", Tag: "customers", Summary: "Add an address to a customer", Request: schemas.AddressInputSchema{}, Response: schemas.AddressOutputSchema{}, Status: http.StatusCreated},
+	{Method: "PATCH", Path: "/customers/{id}/addresses/{addressId}", Tag: "customers", Summary: "Update an address of a customer", Request: schemas.AddressPatchInputSchema{}, Response: schemas.AddressOutputSchema{}},
+	{Method: "DELETE", Path: "/customers/{id}/addresses/{addressId}", Tag: "customers", Summary: "Remove an address of a customer", Status: http.StatusNoContent},
+	{Method: "POST", Path: "/customers/{id}/restore", Tag: "customers", Summary: "Restore a deleted customer", Response: schemas.CustomerOutputSchema{}},
+	{Method: "DELETE", Path: "/customers/{id}/purge", Tag: "customers", Summary: "Permanently remove a deleted customer", Status: http.StatusNoContent, Admin: true},
+
+	{Method: "GET", Path: "/cakes/", Tag: "cakes", Summary: "List cakes", Params: []openapi.Parameter{fieldsParam, includeParam("archived", "unavailable", "options", "images"), availableOnParam, cakeCategoryParam, cakeTagsParam, excludeAllergensParam()}, Response: []schemas.CakeOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "POST", Path: "/cakes/", Tag: "cakes", Summary: "Create a cake", Params: []openapi.Parameter{idempotencyKeyParam}, Request: schemas.CakeInputSchema{}, Response: schemas.CakeOutputSchema{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/cakes/bulk", Tag: "cakes", Summary: "Create many cakes", Params: bulkParams, Request: []schemas.CakeInputSchema{}, Response: schemas.BulkOutputSchema{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/cakes/{id}", Tag: "cakes", Summary: "Get a cake", Params: []openapi.Parameter{fieldsParam, includeParam("options", "images")}, Response: schemas.CakeOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "PATCH", Path: "/cakes/{id}", Tag: "cakes", Summary: "Update a cake", Request: schemas.CakePatchInputSchema{}, Response: schemas.CakeOutputSchema{}},
+	{Method: "DELETE", Path: "/cakes/{id}", Tag: "cakes", Summary: "Delete a cake", Status: http.StatusNoContent},
+	{Method: "POST", Path: "/cakes/{id}/restore", Tag: "cakes", Summary: "Restore an archived cake", Response: schemas.CakeOutputSchema{}},
+	{Method: "GET", Path: "/cakes/{id}/options", Tag: "cakes", Summary: "List the variant options of a cake", Response: []schemas.CakeOptionOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "POST", Path: "/cakes/{id}/options", Tag: "cakes", Summary: "Add a variant option to a cake", Request: schemas.CakeOptionInputSchema{}, Response: schemas.CakeOptionOutputSchema{}, Status: http.StatusCreated},
+	{Method: "PATCH", Path: "/cakes/{id}/options/{optionId}", Tag: "cakes", Summary: "Update a variant option of a cake", Request: schemas.CakeOptionPatchInputSchema{}, Response: schemas.CakeOptionOutputSchema{}},
+	{Method: "DELETE", Path: "/cakes/{id}/options/{optionId}", Tag: "cakes", Summary: "Delete a variant option of a cake", Status: http.StatusNoContent},
+	{Method: "GET", Path: "/cakes/{id}/images", Tag: "cakes", Summary: "List the images of a cake", Response: []schemas.CakeImageOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "POST", Path: "/cakes/{id}/images", Tag: "cakes", Summary: "Upload images of a cake", RequestFiles: controllers.ImageTypes, FormFiles: "images", Response: []schemas.CakeImageOutputSchema{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/cakes/{id}/images/{imageId}", Tag: "cakes", Summary: "Get an image of a cake", ResponseFiles: controllers.ImageTypes},
+	{Method: "DELETE", Path: "/cakes/{id}/images/{imageId}", Tag: "cakes", Summary: "Delete an image of a cake", Status: http.StatusNoContent},
+	{Method: "GET", Path: "/cakes/{id}/images/{imageId}/thumbnail", Tag: "cakes", Summary: "Get the thumbnail of an image of a cake", ResponseFiles: []string{"image/jpeg", "image/png"}},
+
+	{Method: "GET", Path: "/orders/", Tag: "orders", Summary: "List orders", Params: append([]openapi.Parameter{fieldsParam, includeDeletedParam, includeParam("customer", "cake", "address")}, orderFilterParams...), Response: []schemas.OrderOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "POST", Path: "/orders/", Tag: "orders", Summary: "Create an order", Params: []openapi.Parameter{idempotencyKeyParam}, Request: schemas.OrderInputSchema{}, Response: schemas.OrderOutputSchema{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/orders/trash", Tag: "orders", Summary: "List deleted orders", Params: []openapi.Parameter{fieldsParam, includeParam("customer", "cake", "address")}, Response: []schemas.OrderOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "PATCH", Path: "/orders/bulk", Tag: "orders", Summary: "Update many orders", Params: bulkParams, Request: []schemas.OrderBulkPatchInputSchema{}, Response: schemas.BulkOutputSchema{}},
+	{Method: "GET", Path: "/orders/{id}", Tag: "orders", Summary: "Get an order", Params: []openapi.Parameter{fieldsParam, includeParam("customer", "cake", "address")}, Response: schemas.OrderOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "PATCH", Path: "/orders/{id}", Tag: "orders", Summary: "Update an order", Request: schemas.OrderPatchInputSchema{}, Response: schemas.OrderOutputSchema{}},
+	{Method: "DELETE", Path: "/orders/{id}", Tag: "orders", Summary: "Delete an order", Status: http.StatusNoContent},
+	{Method: "POST", Path: "/orders/{id}/restore", Tag: "orders", Summary: "Restore a deleted order", Response: schemas.OrderOutputSchema{}},
+	{Method: "DELETE", Path: "/orders/{id}/purge", Tag: "orders", Summary: "Permanently remove a deleted order", Status: http.StatusNoContent, Admin: true},
+
+	{Method: "GET", Path: "/delivery-zones/", Tag: "delivery-zones", Summary: "List the delivery zones", Response: []schemas.DeliveryZoneOutputSchema{}, ResponseFiles: negotiatedTypes},
+	{Method: "POST", Path: "/delivery-zones/", Tag: "delivery-zones", Summary: "Create a delivery zone", Request: schemas.DeliveryZoneInputSchema{}, Response: schemas.DeliveryZoneOutputSchema{}, Status: http.StatusCreated},
+	{Method: "PATCH", Path: "/delivery-zones/{id}", Tag: "delivery-zones", Summary: "Update a delivery zone", Request: schemas.DeliveryZonePatchInputSchema{}, Response: schemas.DeliveryZoneOutputSchema{}},
+	{Method: "DELETE", Path: "/delivery-zones/{id}", Tag: "delivery-zones", Summary: "Delete a delivery zone", Status: http.StatusNoContent},
+
+	{Method: "GET", Path: "/search", Tag: "search", Summary: "Search customers, cakes and order notes", Params: searchParams, Response: schemas.SearchOutputSchema{}, ResponseFiles: negotiatedTypes},
+
+	{Method: "POST", Path: "/import/customers", Tag: "spreadsheets", Summary: "Import customers from a spreadsheet", Params: bulkParams, RequestFiles: spreadsheetTypes, Response: schemas.BulkOutputSchema{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/import/cakes", Tag: "spreadsheets", Summary: "Import cakes from a spreadsheet", Params: bulkParams, RequestFiles: spreadsheetTypes, Response: schemas.BulkOutputSchema{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/import/orders", Tag: "spreadsheets", Summary: "Import orders from a spreadsheet", Params: bulkParams, RequestFiles: spreadsheetTypes, Response: schemas.BulkOutputSchema{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/export/customers", Tag: "spreadsheets", Summary: "Export the customers", Params: []openapi.Parameter{exportFormatParam}, ResponseFiles: spreadsheetTypes},
+	{Method: "GET", Path: "/export/cakes", Tag: "spreadsheets", Summary: "Export the cakes", Params: []openapi.Parameter{exportFormatParam}, ResponseFiles: spreadsheetTypes},
+	{Method: "GET", Path: "/export/orders", Tag: "spreadsheets", Summary: "Export the orders", Params: []openapi.Parameter{exportFormatParam}, ResponseFiles: spreadsheetTypes},
+
+	{Method: "GET", Path: "/openapi.json", Tag: "docs", Summary: "OpenAPI specification"},
+	{Method: "GET", Path: "/docs", Tag: "docs", Summary: "API documentation page"},
+}
+
+// idempotencyKeyParam is taken by the routes creating customers, cakes and
+// orders, wrapped by the idempotency middleware
+var idempotencyKeyParam = openapi.Parameter{
+	Name:        middlewares.IdempotencyKeyHeader,
+	In:          "header",
+	Description: "Retries with the same key replay the first response",
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+var searchParams = []openapi.Parameter{
+	{Name: "q", In: "query", Required: true, Description: "Terms searched ignoring case and accents", Schema: &openapi.Schema{Type: "string"}},
+	{Name: "limit", In: "query", Description: "Number of results of each type, from 1 to 50", Schema: &openapi.Schema{Type: "integer"}},
+}
+
+var fieldsParam = openapi.Parameter{
+	Name:        "fields",
+	In:          "query",
+	Description: "Comma separated list of the fields to return, such as id,name",
+	Schema:      &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}},
+}
+
+var includeDeletedParam = openapi.Parameter{
+	Name:        "includeDeleted",
+	In:          "query",
+	Description: "Also list the deleted resources",
+	Schema:      &openapi.Schema{Type: "boolean"},
+}
+
+var customerTagsParam = openapi.Parameter{
+	Name:        "tags",
+	In:          "query",
+	Description: "Comma separated list of tags the customers must all have, such as vip,family",
+	Schema:      &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}},
+}
+
+var availableOnParam = openapi.Parameter{
+	Name:        "availableOn",
+	In:          "query",
+	Description: "Date the listed cakes must be orderable on, today by default",
+	Schema:      &openapi.Schema{Type: "string", Format: "date"},
+}
+
+var cakeCategoryParam = openapi.Parameter{
+	Name:        "category",
+	In:          "query",
+	Description: "Category of the listed cakes, such as christmas",
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+var cakeTagsParam = openapi.Parameter{
+	Name:        "tags",
+	In:          "query",
+	Description: "Comma separated list of tags the cakes must all have, such as chocolate,kids",
+	Schema:      &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}},
+}
+
+// bulkParams documents the options of the bulk endpoints and imports
+var bulkParams = []openapi.Parameter{
+	{Name: "mode", In: "query", Description: "atomic applies every item or none of them, partial applies the valid items", Schema: &openapi.Schema{Type: "string", Enum: []any{"atomic", "partial"}}},
+	{Name: "dryRun", In: "query", Description: "Report the result of every item without applying them", Schema: &openapi.Schema{Type: "boolean"}},
+}
+
+// spreadsheetTypes are the media types of the imported and exported files
+var spreadsheetTypes = []string{spreadsheet.CSV, spreadsheet.XLSX}
+
+// negotiatedTypes are the media types the resources can be requested as with
+// the Accept header, besides JSON
+var negotiatedTypes = []string{httphelpers.CSVContentType, httphelpers.XMLContentType}
+
+var exportFormatParam = openapi.Parameter{
+	Name:   "format",
+	In:     "query",
+	Schema: &openapi.Schema{Type: "string", Enum: []any{"csv", "xlsx"}},
+}
+
+// orderFilterParams documents the filters of the order lists
+var orderFilterParams = []openapi.Parameter{
+	{Name: "status", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{"delivered", "pending"}}},
+	{Name: "from", In: "query", Description: "Orders created from this date or timestamp", Schema: &openapi.Schema{Type: "string"}},
+	{Name: "to", In: "query", Description: "Orders created up to this date or timestamp", Schema: &openapi.Schema{Type: "string"}},
+}
+
+// includeParam documents the ?include= query parameter accepting the given values.
+func includeParam(values ...string) openapi.Parameter {
+	enum := make([]any, len(values))
+	for i, value := range values {
+		enum[i] = value
+	}
+	return openapi.Parameter{
+		Name:        "include",
+		In:          "query",
+		Description: "Comma separated list of what to include in the response",
+		Schema:      &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string", Enum: enum}},
+	}
+}
+
+func excludeAllergensParam() openapi.Parameter {
+	enum := make([]any, len(models.Allergens))
+	for i, allergen := range models.Allergens {
+		enum[i] = allergen
+	}
+	return openapi.Parameter{
+		Name:        "excludeAllergens",
+		In:          "query",
+		Description: "Comma separated list of allergens the cakes must not hold, such as gluten,lactose",
+		Schema:      &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string", Enum: enum}},
+	}
+}
+
+// SetupDocsRoutes serves the OpenAPI document at /openapi.json and the
+// documentation page at /docs, generated from apiRoutes.
+func SetupDocsRoutes(baseRouter *mux.Router) {
+	var doc *openapi.Document
+
+	baseRouter.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", httphelpers.JSONContentType)
+		if err := json.NewEncoder(w).Encode(doc); err != nil {
+			log.Println("Cannot write the OpenAPI document", err)
+		}
+	}).Methods("GET")
+	baseRouter.HandleFunc("/docs", openapi.UIHandler("/openapi.json")).Methods("GET")
+
+	info := openapi.Info{
+		Title:       "Confectionery API",
+		Description: "API where a grandma keeps the customers, cakes and orders of her confectionery.",
+		Version:     "1.0.0",
+	}
+
+	doc = openapi.Build(info, apiRoutes, schemas.Problem{})
+}
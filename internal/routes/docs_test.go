@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/controllers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/middlewares"
+	"github.com/gorilla/mux"
+)
+
+// TestAPIRoutesMatchRouter fails when a route is registered without being
+// documented on apiRoutes, or documented without being registered.
+func TestAPIRoutesMatchRouter(t *testing.T) {
+	router := mux.NewRouter()
+	idempotency := middlewares.NewIdempotency(nil)
+	SetupCustomersRoutes(router, controllers.NewCustomerController(nil, nil), idempotency)
+	SetupCakeRoutes(router, controllers.NewCakeController(nil, nil, nil), idempotency)
+	SetupOrdersRoutes(router, controllers.NewOrdersController(nil, nil), idempotency)
+	SetupDeliveryZoneRoutes(router, controllers.NewDeliveryZoneController(nil, nil))
+	SetupSearchRoutes(router, controllers.NewSearchController(nil, nil))
+	SetupSpreadsheetRoutes(router, controllers.NewSpreadsheetController(nil, nil))
+	SetupDocsRoutes(router)
+
+	var registered []string
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+		for _, method := range methods {
+			registered = append(registered, method+" "+path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var documented []string
+	for _, route := range apiRoutes {
+		key := route.Method + " " + route.Path
+		if slices.Contains(documented, key) {
+			t.Errorf("route documented twice: %s", key)
+		}
+		documented = append(documented, key)
+	}
+
+	for _, key := range registered {
+		if !slices.Contains(documented, key) {
+			t.Errorf("undocumented route: %s", key)
+		}
+	}
+	for _, key := range documented {
+		if !slices.Contains(registered, key) {
+			t.Errorf("documented route not registered: %s", key)
+		}
+	}
+}
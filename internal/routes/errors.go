@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/middlewares"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/errorhandling"
+	"github.com/gorilla/mux"
+)
+
+// SetupErrorHandlers makes the router answer unknown paths and methods with
+// problem responses, like every other error returned by the API.
+func SetupErrorHandlers(baseRouter *mux.Router) {
+	baseRouter.NotFoundHandler = middlewares.RequestID(middlewares.Language(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			errorhandling.ProblemResponse(w, http.StatusNotFound, "Resource not found")
+		},
+	)))
+
+	baseRouter.MethodNotAllowedHandler = middlewares.RequestID(middlewares.Language(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			errorhandling.ProblemResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		},
+	)))
+}
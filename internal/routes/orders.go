@@ -1,17 +1,24 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/LeandroDeJesus-S/confectionery/internal/controllers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/middlewares"
 	"github.com/gorilla/mux"
 )
 
-func SetupOrdersRoutes(baseRouter *mux.Router, c *controllers.OrdersController) {
+func SetupOrdersRoutes(baseRouter *mux.Router, c *controllers.OrdersController, idempotency *middlewares.Idempotency) {
 	r := baseRouter.PathPrefix("/orders").Subrouter()
 
 	r.HandleFunc("/", c.GetOrders).Methods("GET")
-	r.HandleFunc("/", c.CreateOrder).Methods("POST")
+	r.Handle("/", idempotency.Handler(http.HandlerFunc(c.CreateOrder))).Methods("POST")
+	r.HandleFunc("/trash", c.GetOrdersTrash).Methods("GET")
+	r.HandleFunc("/bulk", c.UpdateOrders).Methods("PATCH")
 
 	r.HandleFunc("/{id}", c.GetOrder).Methods("GET")
 	r.HandleFunc("/{id}", c.UpdateOrder).Methods("PATCH")
 	r.HandleFunc("/{id}", c.DeleteOrder).Methods("DELETE")
+	r.HandleFunc("/{id}/restore", c.RestoreOrder).Methods("POST")
+	r.Handle("/{id}/purge", middlewares.RequireAdmin(http.HandlerFunc(c.PurgeOrder))).Methods("DELETE")
 }
@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/LeandroDeJesus-S/confectionery/internal/controllers"
+	"github.com/gorilla/mux"
+)
+
+func SetupSearchRoutes(baseRouter *mux.Router, c *controllers.SearchController) {
+	baseRouter.HandleFunc("/search", c.Search).Methods("GET")
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/LeandroDeJesus-S/confectionery/internal/controllers"
+	"github.com/gorilla/mux"
+)
+
+func SetupSpreadsheetRoutes(baseRouter *mux.Router, c *controllers.SpreadsheetController) {
+	importRouter := baseRouter.PathPrefix("/import").Subrouter()
+	importRouter.HandleFunc("/customers", c.ImportCustomers).Methods("POST")
+	importRouter.HandleFunc("/cakes", c.ImportCakes).Methods("POST")
+	importRouter.HandleFunc("/orders", c.ImportOrders).Methods("POST")
+
+	exportRouter := baseRouter.PathPrefix("/export").Subrouter()
+	exportRouter.HandleFunc("/customers", c.ExportCustomers).Methods("GET")
+	exportRouter.HandleFunc("/cakes", c.ExportCakes).Methods("GET")
+	exportRouter.HandleFunc("/orders", c.ExportOrders).Methods("GET")
+}
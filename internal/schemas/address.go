@@ -0,0 +1,43 @@
+package schemas
+
+// AddressInputSchema is the schema for Addresses creation. The first address
+// of a customer becomes its default address.
+type AddressInputSchema struct {
+	Label        string `json:"label" validate:"max=50"`
+	Street       string `json:"street" validate:"required,max=255"`
+	Number       string `json:"number" validate:"required,max=20"`
+	Complement   string `json:"complement" validate:"max=100"`
+	Neighborhood string `json:"neighborhood" validate:"required,max=100"`
+	City         string `json:"city" validate:"required,max=100"`
+	CEP          string `json:"cep" validate:"required,cep"`
+	Default      bool   `json:"default"`
+}
+
+// AddressPatchInputSchema is the JSON Merge Patch schema for Addresses
+// update. Setting default to true makes it the default address of the
+// customer, while the default address cannot be unset directly.
+type AddressPatchInputSchema struct {
+	Label        Optional[string] `json:"label" validate:"omitnil,max=50"`
+	Street       Optional[string] `json:"street" validate:"omitnil,required,max=255"`
+	Number       Optional[string] `json:"number" validate:"omitnil,required,max=20"`
+	Complement   Optional[string] `json:"complement" validate:"omitnil,max=100"`
+	Neighborhood Optional[string] `json:"neighborhood" validate:"omitnil,required,max=100"`
+	City         Optional[string] `json:"city" validate:"omitnil,required,max=100"`
+	CEP          Optional[string] `json:"cep" validate:"omitnil,required,cep"`
+	Default      Optional[bool]   `json:"default" validate:"omitnil,eq=true"`
+}
+
+// AddressOutputSchema represents the address returned by the API, with the
+// CEP formatted as 01310-100
+type AddressOutputSchema struct {
+	ID           uint   `json:"id"`
+	CustomerID   uint   `json:"customerId"`
+	Label        string `json:"label"`
+	Street       string `json:"street"`
+	Number       string `json:"number"`
+	Complement   string `json:"complement"`
+	Neighborhood string `json:"neighborhood"`
+	City         string `json:"city"`
+	CEP          string `json:"cep"`
+	Default      bool   `json:"default"`
+}
@@ -0,0 +1,34 @@
+package schemas
+
+// BulkOutputSchema is the response of the bulk endpoints, holding the result
+// of every item in the order they were sent
+type BulkOutputSchema struct {
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// BulkItemResult is the outcome of a single item of a bulk request. Status
+// is the status code the item would get on the single resource endpoint, or
+// 424 Failed Dependency for the items rolled back because another one failed.
+type BulkItemResult struct {
+	Index int `json:"index"`
+	// Row is the line of the spreadsheet of the imported items
+	Row    int      `json:"row,omitempty"`
+	ID     uint     `json:"id,omitempty"`
+	Status int      `json:"status"`
+	Data   any      `json:"data,omitempty"`
+	Error  *Problem `json:"error,omitempty"`
+}
+
+// CustomerBulkDeleteInputSchema is an item of DELETE /customers/bulk
+type CustomerBulkDeleteInputSchema struct {
+	ID uint `json:"id" validate:"required"`
+}
+
+// OrderBulkPatchInputSchema is an item of PATCH /orders/bulk, applying the
+// JSON Merge Patch to the order of the given ID
+type OrderBulkPatchInputSchema struct {
+	ID    uint                  `json:"id" validate:"required"`
+	Patch OrderPatchInputSchema `json:"patch"`
+}
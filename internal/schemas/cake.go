@@ -1,18 +1,86 @@
 package schemas
 
+import (
+	"encoding/json"
+	"time"
+)
 
 type CakeInputSchema struct {
-	Name  string `json:"name" validate:"required"`
-	Price uint64  `json:"price" validate:"required"`
+	Name      string           `json:"name" validate:"required"`
+	Price     uint64           `json:"price" validate:"required"`
+	Allergens []string         `json:"allergens" validate:"unique,dive,allergen"`
+	Nutrition *NutritionSchema `json:"nutrition" validate:"omitnil"`
+	// PersonalizationSchema is the JSON Schema of an object, validating the
+	// personalization of the orders of the cake
+	PersonalizationSchema json.RawMessage `json:"personalizationSchema" validate:"omitempty,jsonschema"`
+	Category              string          `json:"category" validate:"omitempty,tag"`
+	Tags                  []string        `json:"tags" validate:"max=20,dive,tag"`
+	// Available defaults to true
+	Available *bool `json:"available"`
+	// AvailableFrom and AvailableUntil are YYYY-MM-DD dates, both included
+	AvailableFrom  *string  `json:"availableFrom" validate:"omitnil,date"`
+	AvailableUntil *string  `json:"availableUntil" validate:"omitnil,date"`
+	Weekdays       []string `json:"weekdays" validate:"unique,dive,weekday"`
 }
 
+// NutritionSchema holds the nutrition facts of a serving of a cake. The
+// serving size is in grams, the energy in kcal, the sodium in milligrams and
+// the other facts in grams.
+type NutritionSchema struct {
+	ServingSize   float64 `json:"servingSize" validate:"gt=0"`
+	Energy        float64 `json:"energy" validate:"gte=0"`
+	Carbohydrates float64 `json:"carbohydrates" validate:"gte=0"`
+	Sugars        float64 `json:"sugars" validate:"gte=0,ltefield=Carbohydrates"`
+	Proteins      float64 `json:"proteins" validate:"gte=0"`
+	TotalFat      float64 `json:"totalFat" validate:"gte=0"`
+	SaturatedFat  float64 `json:"saturatedFat" validate:"gte=0,ltefield=TotalFat"`
+	TransFat      float64 `json:"transFat" validate:"gte=0,ltefield=TotalFat"`
+	Fiber         float64 `json:"fiber" validate:"gte=0"`
+	Sodium        float64 `json:"sodium" validate:"gte=0"`
+}
+
+// CakePatchInputSchema is the JSON Merge Patch schema for Cakes update
 type CakePatchInputSchema struct {
-	Name  string `json:"name,omitempty"`
-	Price *uint64  `json:"price,omitempty"`
+	Name  Optional[string] `json:"name" validate:"omitnil,required"`
+	Price Optional[uint64] `json:"price" validate:"omitnil,required"`
+	// Allergens replace the current ones, and are cleared with null
+	Allergens Optional[[]string] `json:"allergens" validate:"omitnil,unique,dive,allergen"`
+	// Nutrition replaces the current nutrition facts, and removes them with null
+	Nutrition Optional[*NutritionSchema] `json:"nutrition" validate:"omitnil"`
+	// PersonalizationSchema replaces the current schema, and is removed with
+	// null. The orders already placed keep their personalization.
+	PersonalizationSchema Optional[json.RawMessage] `json:"personalizationSchema" validate:"omitnil,omitempty,jsonschema"`
+	// Category is removed with null or ""
+	Category  Optional[string]   `json:"category" validate:"omitnil,omitempty,tag"`
+	Tags      Optional[[]string] `json:"tags" validate:"omitnil,max=20,dive,tag"`
+	Available Optional[bool]     `json:"available"`
+	// AvailableFrom and AvailableUntil are removed with null, and Weekdays
+	// are cleared with null
+	AvailableFrom  Optional[string]   `json:"availableFrom" validate:"omitnil,omitempty,date"`
+	AvailableUntil Optional[string]   `json:"availableUntil" validate:"omitnil,omitempty,date"`
+	Weekdays       Optional[[]string] `json:"weekdays" validate:"omitnil,unique,dive,weekday"`
 }
 
 type CakeOutputSchema struct {
-	ID    uint   `json:"id"`
-	Name  string `json:"name"`
-	Price uint64  `json:"price"`
+	ID        uint     `json:"id"`
+	Name      string   `json:"name"`
+	Price     uint64   `json:"price"`
+	Allergens []string `json:"allergens"`
+	// Nutrition is null for the cakes without nutrition facts
+	Nutrition *NutritionSchema `json:"nutrition"`
+	// PersonalizationSchema is null for the cakes without personalization
+	PersonalizationSchema json.RawMessage `json:"personalizationSchema"`
+	Category              string          `json:"category"`
+	Tags                  []string        `json:"tags"`
+	Available             bool            `json:"available"`
+	// AvailableFrom and AvailableUntil are null when the availability is not
+	// limited by dates, and Weekdays empty when it is not limited by weekdays
+	AvailableFrom  *string    `json:"availableFrom"`
+	AvailableUntil *string    `json:"availableUntil"`
+	Weekdays       []string   `json:"weekdays"`
+	ArchivedAt     *time.Time `json:"archivedAt,omitempty"`
+	// Options are only embedded with ?include=options
+	Options []CakeOptionOutputSchema `json:"options,omitzero"`
+	// Images hold the URLs of the images of the cake and of their thumbnails
+	Images []CakeImageOutputSchema `json:"images"`
 }
@@ -0,0 +1,19 @@
+package schemas
+
+import "time"
+
+// CakeImageOutputSchema represents the photo of a cake returned by the API.
+// The URLs are relative to the API and serve files that never change, so they
+// are cached by the clients. The width, height and size, in bytes, are the
+// ones of the uploaded image.
+type CakeImageOutputSchema struct {
+	ID           uint      `json:"id"`
+	CakeID       uint      `json:"cakeId"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnailUrl"`
+	ContentType  string    `json:"contentType"`
+	Width        uint      `json:"width"`
+	Height       uint      `json:"height"`
+	Size         int64     `json:"size"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,35 @@
+package schemas
+
+// CakeOptionInputSchema is the schema for the creation of the variant
+// options of a cake. The SKU is stored in upper case, and only the size
+// options may replace the price of the cake.
+type CakeOptionInputSchema struct {
+	Kind       string  `json:"kind" validate:"required,oneof=size flavor filling frosting"`
+	Name       string  `json:"name" validate:"required,max=100"`
+	SKU        string  `json:"sku" validate:"required,max=30,alphanum"`
+	Price      *uint64 `json:"price" validate:"excluded_unless=Kind size"`
+	PriceDelta int64   `json:"priceDelta"`
+}
+
+// CakeOptionPatchInputSchema is the JSON Merge Patch schema for the update of
+// the cake options. Setting price to null makes the option keep the price of
+// the cake. The orders already placed keep the price they were placed with.
+type CakeOptionPatchInputSchema struct {
+	Name       Optional[string] `json:"name" validate:"omitnil,required,max=100"`
+	SKU        Optional[string] `json:"sku" validate:"omitnil,required,max=30,alphanum"`
+	Price      Optional[uint64] `json:"price"`
+	PriceDelta Optional[int64]  `json:"priceDelta"`
+}
+
+// CakeOptionOutputSchema represents the cake option returned by the API.
+// Prices are in cents: price, when not null, replaces the price of the cake,
+// and priceDelta is added to it.
+type CakeOptionOutputSchema struct {
+	ID         uint    `json:"id"`
+	CakeID     uint    `json:"cakeId"`
+	Kind       string  `json:"kind"`
+	Name       string  `json:"name"`
+	SKU        string  `json:"sku"`
+	Price      *uint64 `json:"price"`
+	PriceDelta int64   `json:"priceDelta"`
+}
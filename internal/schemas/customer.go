@@ -1,23 +1,77 @@
 package schemas
 
-// CustomerInputSchema is the schema for Customers creation
+import "time"
+
+// CustomerInputSchema is the schema for Customers creation. At least an
+// email or a phone is required.
 type CustomerInputSchema struct {
-	Fname                string `json:"fName" validate:"required"`
-	Lname                string `json:"lName" validate:"required"`
-	Email                string `json:"email" validate:"required,email"`
+	Fname string `json:"fName" validate:"required"`
+	Lname string `json:"lName" validate:"required"`
+	Email string `json:"email" validate:"omitempty,email"`
+	// Phone takes Brazilian numbers with the area code, such as (11) 91234-5678,
+	// or international ones starting with +
+	Phone string `json:"phone" validate:"omitempty,phone"`
+	CPF   string `json:"cpf" validate:"omitempty,cpf"`
+	Notes string `json:"notes" validate:"max=1000"`
+	// Tags are compared ignoring case, such as "vip", "wholesale" or "family"
+	Tags      []string        `json:"tags" validate:"max=20,dive,tag"`
+	Allergies []AllergySchema `json:"allergies" validate:"unique=Allergen,dive"`
+	Diet      []string        `json:"diet" validate:"unique,dive,diet"`
+}
+
+// AllergySchema is an allergy of a customer. Orders of cakes holding the
+// allergen are refused for severe allergies and carry a warning otherwise.
+type AllergySchema struct {
+	Allergen string `json:"allergen" validate:"allergen"`
+	Severe   bool   `json:"severe"`
 }
 
 // CustomerOutputSchema represents the customer schema returned by the API
 type CustomerOutputSchema struct {
-	ID    uint   `json:"id"`
-	Fname string `json:"fName"`
-	Lname string `json:"lName"`
-	Email string `json:"email"`
+	ID    uint    `json:"id"`
+	Fname string  `json:"fName"`
+	Lname string  `json:"lName"`
+	Email *string `json:"email"`
+	// Phone is formatted in E.164, such as +5511912345678
+	Phone *string `json:"phone"`
+	// CPF is formatted as 123.456.789-09
+	CPF       *string         `json:"cpf"`
+	Notes     string          `json:"notes"`
+	Tags      []string        `json:"tags"`
+	Allergies []AllergySchema `json:"allergies"`
+	Diet      []string        `json:"diet"`
+	// Active is false for the deleted customers, listed on the trash
+	Active bool `json:"active"`
+	// CustomerStats is left out of the customers embedded in other resources
+	*CustomerStats
+	// Orders are only embedded with ?include=orders
+	Orders []OrderOutputSchema `json:"orders,omitzero"`
+	// Addresses are only embedded with ?include=addresses
+	Addresses []AddressOutputSchema `json:"addresses,omitzero"`
 }
 
-// CustomerPatchInputSchema is the schema for Customers update
+// CustomerStats holds the lifetime stats of a customer, computed from the
+// orders that were not deleted
+type CustomerStats struct {
+	OrderCount int64 `json:"orderCount"`
+	// TotalSpent is the sum of the orders in cents
+	TotalSpent  uint64     `json:"totalSpent"`
+	LastOrderAt *time.Time `json:"lastOrderAt"`
+}
+
+// CustomerPatchInputSchema is the JSON Merge Patch schema for Customers
+// update. Email, phone and CPF are removed with null, as long as the customer
+// keeps an email or a phone.
 type CustomerPatchInputSchema struct {
-	Fname                string `json:"fName,omitempty"`
-	Lname                string `json:"lName,omitempty"`
-	Email                string `json:"email,omitempty" validate:"email"`
-}
\ No newline at end of file
+	Fname Optional[string] `json:"fName" validate:"omitnil,required"`
+	Lname Optional[string] `json:"lName" validate:"omitnil,required"`
+	Email Optional[string] `json:"email" validate:"omitnil,omitempty,email"`
+	Phone Optional[string] `json:"phone" validate:"omitnil,omitempty,phone"`
+	CPF   Optional[string] `json:"cpf" validate:"omitnil,omitempty,cpf"`
+	Notes Optional[string] `json:"notes" validate:"omitnil,max=1000"`
+	// Tags, allergies and diet replace the current lists, and are cleared
+	// with null
+	Tags      Optional[[]string]        `json:"tags" validate:"omitnil,max=20,dive,tag"`
+	Allergies Optional[[]AllergySchema] `json:"allergies" validate:"omitnil,unique=Allergen,dive"`
+	Diet      Optional[[]string]        `json:"diet" validate:"omitnil,unique,dive,diet"`
+}
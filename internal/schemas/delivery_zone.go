@@ -0,0 +1,35 @@
+package schemas
+
+// DeliveryZoneInputSchema is the schema for Delivery Zones creation. A zone
+// is defined by a range of CEPs or by a neighborhood, optionally of a city.
+type DeliveryZoneInputSchema struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	CEPStart     string `json:"cepStart" validate:"required_without=Neighborhood,required_with=CEPEnd,omitempty,cep"`
+	CEPEnd       string `json:"cepEnd" validate:"required_with=CEPStart,omitempty,cep"`
+	Neighborhood string `json:"neighborhood" validate:"max=100"`
+	City         string `json:"city" validate:"max=100"`
+	// Fee is the delivery fee in cents
+	Fee uint64 `json:"fee"`
+}
+
+// DeliveryZonePatchInputSchema is the JSON Merge Patch schema for Delivery
+// Zones update
+type DeliveryZonePatchInputSchema struct {
+	Name         Optional[string] `json:"name" validate:"omitnil,required,max=100"`
+	CEPStart     Optional[string] `json:"cepStart" validate:"omitnil,omitempty,cep"`
+	CEPEnd       Optional[string] `json:"cepEnd" validate:"omitnil,omitempty,cep"`
+	Neighborhood Optional[string] `json:"neighborhood" validate:"omitnil,max=100"`
+	City         Optional[string] `json:"city" validate:"omitnil,max=100"`
+	Fee          Optional[uint64] `json:"fee"`
+}
+
+// DeliveryZoneOutputSchema represents the delivery zone returned by the API
+type DeliveryZoneOutputSchema struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	CEPStart     string `json:"cepStart"`
+	CEPEnd       string `json:"cepEnd"`
+	Neighborhood string `json:"neighborhood"`
+	City         string `json:"city"`
+	Fee          uint64 `json:"fee"`
+}
@@ -0,0 +1,52 @@
+package schemas
+
+import "encoding/json"
+
+// PatchField is implemented by the fields of the patch input schemas.
+type PatchField interface {
+	// Patched returns the value to be written and whether the field was
+	// present on the patch document.
+	Patched() (any, bool)
+
+	// ValidationValue returns the value to be written, or a nil pointer
+	// when the field is absent, so "omitnil" skips the rules of the fields
+	// left untouched.
+	ValidationValue() any
+}
+
+// Optional is a field of a JSON Merge Patch document (RFC 7396). It tells
+// apart a field absent from the document, which must be left untouched, from
+// a field explicitly set to null, which resets it to the zero value of T.
+type Optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// UnmarshalJSON is only called by encoding/json when the field is present
+// on the document, marking it as set.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Null = true
+		var zero T
+		o.Value = zero
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// Patched returns the value of the field, which is the zero value of T when
+// the field was set to null.
+func (o Optional[T]) Patched() (any, bool) {
+	return o.Value, o.Set
+}
+
+// ValidationValue returns the value of the field, or a nil pointer to T when
+// the field is absent from the document.
+func (o Optional[T]) ValidationValue() any {
+	if !o.Set {
+		return (*T)(nil)
+	}
+	return o.Value
+}
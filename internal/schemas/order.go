@@ -1,17 +1,105 @@
 package schemas
 
-// Order represents the schema of an order made by a customer
+import (
+	"encoding/json"
+	"time"
+)
+
+// OrderOutputSchema represents the order returned by the API, embedding the
+// customer, cake and address requested with ?include=customer,cake,address
+type OrderOutputSchema struct {
+	ID         uint   `json:"id"`
+	CustomerID uint   `json:"customerId"`
+	CakeID     uint   `json:"cakeId"`
+	Qtd        uint   `json:"qtd"`
+	Delivered  bool   `json:"delivered"`
+	Notes      string `json:"notes"`
+	Pickup     bool   `json:"pickup"`
+	// AddressID is null for the orders picked up at the shop
+	AddressID *uint `json:"addressId"`
+	// DueDate is null for the orders placed without a due date
+	DueDate *string `json:"dueDate"`
+	// OptionIDs are the options of the cake, one of each kind it offers, and
+	// SKU joins their SKUs
+	OptionIDs []uint `json:"optionIds"`
+	SKU       string `json:"sku"`
+	// Personalization is null for the orders without personalization
+	Personalization json.RawMessage `json:"personalization"`
+	// UnitPrice, DeliveryFee and Total are in cents, the total including the fee
+	UnitPrice   uint64 `json:"unitPrice"`
+	DeliveryFee uint64 `json:"deliveryFee"`
+	Total       uint64 `json:"total"`
+	// AllergenWarnings lists the allergens of the cake the customer has mild
+	// allergies to
+	AllergenWarnings []string `json:"allergenWarnings"`
+	// Allergens are the current allergens of the cake, only listed on the
+	// order details
+	Allergens []string              `json:"allergens,omitzero"`
+	CreatedAt time.Time             `json:"createdAt"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+	Customer  *CustomerOutputSchema `json:"customer,omitempty"`
+	Cake      *CakeOutputSchema     `json:"cake,omitempty"`
+	Address   *AddressOutputSchema  `json:"address,omitempty"`
+}
+
+// Order represents the schema of an order made by a customer. Orders are
+// delivered to addressId, or to the default address of the customer when it
+// is not given, unless pickup is true.
 type OrderInputSchema struct {
-	CustomerID uint `json:"customerId"`
-	CakeID     uint `json:"cakeId"`
-	Qtd        uint `json:"qtd"`
-	Delivered  bool `json:"delivered"`
+	CustomerID uint   `json:"customerId"`
+	CakeID     uint   `json:"cakeId"`
+	Qtd        uint   `json:"qtd"`
+	Delivered  bool   `json:"delivered"`
+	Notes      string `json:"notes" validate:"max=1000"`
+	Pickup     bool   `json:"pickup"`
+	AddressID  *uint  `json:"addressId" validate:"excluded_if=Pickup true"`
+	OptionIDs  []uint `json:"optionIds" validate:"unique"`
+	// DueDate is the YYYY-MM-DD date the order is picked up or delivered on,
+	// today when not given, which the cake must be available on
+	DueDate *string `json:"dueDate" validate:"omitnil,date"`
+	// Personalization is validated against the personalization schema of
+	// the cake
+	Personalization json.RawMessage `json:"personalization"`
+}
+
+// CustomerOrderInputSchema is the schema of the orders placed through the
+// customer routes, which take the customer from the path
+type CustomerOrderInputSchema struct {
+	CakeID    uint   `json:"cakeId"`
+	Qtd       uint   `json:"qtd"`
+	Delivered bool   `json:"delivered"`
+	Notes     string `json:"notes" validate:"max=1000"`
+	Pickup    bool   `json:"pickup"`
+	AddressID *uint  `json:"addressId" validate:"excluded_if=Pickup true"`
+	OptionIDs []uint `json:"optionIds" validate:"unique"`
+	// DueDate is the YYYY-MM-DD date the order is picked up or delivered on,
+	// today when not given, which the cake must be available on
+	DueDate *string `json:"dueDate" validate:"omitnil,date"`
+	// Personalization is validated against the personalization schema of
+	// the cake
+	Personalization json.RawMessage `json:"personalization"`
 }
 
-// OrderPatchInputSchema is the schema for Orders update
+// OrderPatchInputSchema is the JSON Merge Patch schema for Orders update.
+// Setting qtd, delivered or notes to null resets them to 0, false and "".
+// Setting addressId switches the order to delivery, and setting it to null
+// delivers it to the default address of the customer. Changing customerId
+// without addressId delivers it to the default address of the new customer.
 type OrderPatchInputSchema struct {
-	CustomerID uint `json:"customerId,omitempty"`
-	CakeID     uint `json:"cakeId,omitempty"`
-	Qtd        uint `json:"qtd,omitempty"`
-	Delivered  bool `json:"delivered,omitempty"`
+	CustomerID Optional[uint]   `json:"customerId" validate:"omitnil,required"`
+	CakeID     Optional[uint]   `json:"cakeId" validate:"omitnil,required"`
+	Qtd        Optional[uint]   `json:"qtd"`
+	Delivered  Optional[bool]   `json:"delivered"`
+	Notes      Optional[string] `json:"notes" validate:"omitnil,max=1000"`
+	Pickup     Optional[bool]   `json:"pickup"`
+	AddressID  Optional[uint]   `json:"addressId"`
+	// OptionIDs replace the options of the order, and are needed along with
+	// a cakeId offering options
+	OptionIDs Optional[[]uint] `json:"optionIds" validate:"omitnil,unique"`
+	// DueDate is removed with null, and the availability of the cake is
+	// checked again when it or the cake change
+	DueDate Optional[string] `json:"dueDate" validate:"omitnil,omitempty,date"`
+	// Personalization replaces the current one, and is removed with null.
+	// It is validated again when the cake changes.
+	Personalization Optional[json.RawMessage] `json:"personalization"`
 }
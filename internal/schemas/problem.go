@@ -0,0 +1,25 @@
+package schemas
+
+// Problem represents an error returned by the API, following the
+// RFC 7807 problem details format
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// OrderIDs lists the orders preventing a resource from being deleted
+	OrderIDs []uint `json:"orderIds,omitempty"`
+	// InvalidFields and AllowedFields describe a rejected ?fields= parameter,
+	// and InvalidFields the columns of a rejected spreadsheet row
+	InvalidFields []string `json:"invalidFields,omitempty"`
+	AllowedFields []string `json:"allowedFields,omitempty"`
+}
+
+// FieldError describes why a single field of the request was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
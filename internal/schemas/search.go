@@ -0,0 +1,8 @@
+package schemas
+
+// SearchOutputSchema groups the results of a search by type, best ranked first
+type SearchOutputSchema struct {
+	Customers []CustomerOutputSchema `json:"customers"`
+	Cakes     []CakeOutputSchema     `json:"cakes"`
+	Orders    []OrderOutputSchema    `json:"orders"`
+}
@@ -0,0 +1,275 @@
+// Package search keeps a full-text index of the customers, cakes and order
+// notes. On SQLite builds with FTS5 the index is an FTS5 virtual table ranked
+// with bm25; on other drivers, or when FTS5 is not compiled in, it falls back
+// to a plain table matched with LIKE and ranked by the number of matched
+// terms. Both store the text folded to lower case without diacritics, so the
+// matches are accent- and case-insensitive.
+package search
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/models"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Types of the indexed documents
+const (
+	TypeCustomer = "customer"
+	TypeCake     = "cake"
+	TypeOrder    = "order"
+)
+
+// indexTable is the name of the index, either the FTS5 virtual table or the
+// fallback plain table
+const indexTable = "search_index"
+
+// stopwords are the Portuguese words left out of the queries, so a sentence
+// such as "a Maria do bolo de cenoura" matches on its meaningful terms only
+var stopwords = map[string]bool{
+	"a": true, "o": true, "as": true, "os": true, "e": true, "de": true, "do": true, "da": true,
+	"dos": true, "das": true, "em": true, "no": true, "na": true, "nos": true, "nas": true,
+	"um": true, "uma": true, "com": true, "para": true, "por": true, "que": true,
+}
+
+// Hit is a document matching a query.
+type Hit struct {
+	Type string
+	ID   uint
+}
+
+// document is a row of the fallback index table.
+type document struct {
+	Type    string `gorm:"primaryKey;size:20"`
+	RefID   uint   `gorm:"primaryKey"`
+	Content string `gorm:"not null"`
+}
+
+func (document) TableName() string {
+	return indexTable
+}
+
+// Engine searches the index and keeps it up to date.
+type Engine struct {
+	db  *gorm.DB
+	fts bool
+}
+
+// Setup creates the index, registers the callbacks indexing the customers,
+// cakes and orders whenever they are created or updated, and rebuilds the
+// index from the existing records.
+func Setup(db *gorm.DB) (*Engine, error) {
+	engine := &Engine{db: db}
+
+	if db.Dialector.Name() == "sqlite" {
+		// a fallback table left by a build without FTS5 is kept, since the
+		// statement creating the virtual table would then succeed doing nothing
+		var existing []string
+		err := db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", indexTable).
+			Scan(&existing).Error
+		if err != nil {
+			return nil, err
+		}
+
+		if len(existing) == 0 || strings.Contains(strings.ToUpper(existing[0]), "VIRTUAL") {
+			// the statement fails when FTS5 is not compiled in, which is expected
+			probe := db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)})
+			err = probe.Exec(
+				"CREATE VIRTUAL TABLE IF NOT EXISTS " + indexTable +
+					" USING fts5(type UNINDEXED, ref_id UNINDEXED, content, tokenize = 'unicode61 remove_diacritics 2')",
+			).Error
+			engine.fts = err == nil
+		}
+	}
+
+	if !engine.fts {
+		if err := db.AutoMigrate(&document{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := db.Callback().Create().After("gorm:create").Register("search:index", engine.indexCallback); err != nil {
+		return nil, err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("search:index", engine.indexCallback); err != nil {
+		return nil, err
+	}
+
+	return engine, engine.Rebuild()
+}
+
+// FullText reports whether the index uses SQLite FTS5.
+func (e *Engine) FullText() bool {
+	return e.fts
+}
+
+// Rebuild indexes every customer, cake and order again.
+func (e *Engine) Rebuild() error {
+	return e.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM " + indexTable).Error; err != nil {
+			return err
+		}
+
+		var customers []models.Customer
+		if err := tx.Find(&customers).Error; err != nil {
+			return err
+		}
+		var cakes []models.Cake
+		if err := tx.Find(&cakes).Error; err != nil {
+			return err
+		}
+		var orders []models.Order
+		if err := tx.Find(&orders).Error; err != nil {
+			return err
+		}
+
+		for _, records := range []any{customers, cakes, orders} {
+			if err := e.index(tx, reflect.ValueOf(records)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Search returns up to limit documents of the given type matching any of the
+// terms of the query, best ranked first. The documents are restricted to the
+// records whose IDs are selected by candidates, such as the ones not deleted,
+// so the limit only counts the records returned.
+func (e *Engine) Search(query string, docType string, limit int, candidates *gorm.DB) ([]Hit, error) {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var hits []Hit
+	var err error
+	if e.fts {
+		match := make([]string, len(terms))
+		for i, term := range terms {
+			match[i] = `"` + term + `"*`
+		}
+		err = e.db.Raw(
+			"SELECT type, ref_id AS id FROM "+indexTable+" WHERE "+indexTable+" MATCH ? AND type = ? "+
+				"AND ref_id IN (?) ORDER BY bm25("+indexTable+") LIMIT ?",
+			"content: "+strings.Join(match, " OR "), docType, candidates, limit,
+		).Scan(&hits).Error
+	} else {
+		score := make([]string, len(terms))
+		args := make([]any, 0, len(terms)*2+2)
+		for i, term := range terms {
+			score[i] = "(CASE WHEN content LIKE ? THEN 1 ELSE 0 END)"
+			args = append(args, "%"+term+"%")
+		}
+		args = append(args, docType, candidates, limit)
+		err = e.db.Raw(
+			"SELECT type, ref_id AS id FROM (SELECT type, ref_id, "+strings.Join(score, " + ")+" AS score FROM "+
+				indexTable+") AS scored WHERE score > 0 AND type = ? AND ref_id IN (?) ORDER BY score DESC, ref_id LIMIT ?",
+			args...,
+		).Scan(&hits).Error
+	}
+	return hits, err
+}
+
+// Terms splits the query into the folded terms searched on the index,
+// leaving out the stopwords.
+func Terms(query string) []string {
+	var terms []string
+	for _, term := range strings.FieldsFunc(Fold(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if !stopwords[term] {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Fold returns the text in lower case without diacritics, so "Cenoura" and
+// "JOÃO" are stored and searched as "cenoura" and "joao".
+func Fold(text string) string {
+	folded, _, err := transform.String(
+		transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), text,
+	)
+	if err != nil {
+		folded = text
+	}
+	return strings.ToLower(folded)
+}
+
+// indexCallback indexes the records created or updated by the statement.
+func (e *Engine) indexCallback(tx *gorm.DB) {
+	if tx.Error != nil || tx.Statement.Schema == nil || tx.Statement.RowsAffected == 0 {
+		return
+	}
+
+	// updates with a map on a model hold the record on Statement.Model
+	value := tx.Statement.ReflectValue
+	if _, ok := tx.Statement.Dest.(map[string]any); ok {
+		value = reflect.ValueOf(tx.Statement.Model)
+	}
+
+	if err := e.index(tx.Session(&gorm.Session{NewDB: true}), value); err != nil {
+		tx.AddError(fmt.Errorf("search: %w", err))
+	}
+}
+
+// index writes the documents of the given records, a model, a pointer to a
+// model or a slice of them. Values of other types are ignored.
+func (e *Engine) index(tx *gorm.DB, value reflect.Value) error {
+	value = reflect.Indirect(value)
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		for i := range value.Len() {
+			if err := e.index(tx, value.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !value.IsValid() || !value.CanInterface() {
+		return nil
+	}
+
+	var docType, content string
+	var id uint
+	switch record := value.Interface().(type) {
+	case models.Customer:
+		docType, id = TypeCustomer, record.ID
+		content = strings.Join(append(
+			[]string{record.Fname, record.Lname, deref(record.Email), deref(record.Phone), record.Notes}, record.Tags...,
+		), " ")
+	case models.Cake:
+		docType, id, content = TypeCake, record.ID, record.Name
+	case models.Order:
+		docType, id, content = TypeOrder, record.ID, record.Notes
+	default:
+		return nil
+	}
+	// bulk updates, without the record id, are left for the next rebuild
+	if id == 0 {
+		return nil
+	}
+
+	err := tx.Exec("DELETE FROM "+indexTable+" WHERE type = ? AND ref_id = ?", docType, id).Error
+	if err != nil || strings.TrimSpace(content) == "" {
+		return err
+	}
+	return tx.Exec(
+		"INSERT INTO "+indexTable+" (type, ref_id, content) VALUES (?, ?, ?)", docType, id, Fold(content),
+	).Error
+}
+
+// deref returns the text of an optional field, empty when it is nil.
+func deref(text *string) string {
+	if text == nil {
+		return ""
+	}
+	return *text
+}
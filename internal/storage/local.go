@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultLocalDir is the directory of the local storage when none is given
+const DefaultLocalDir = "uploads"
+
+// Local stores the files on a directory of the local filesystem, keeping the
+// keys as paths relative to it.
+type Local struct {
+	root string
+}
+
+// NewLocal creates the local storage on the given directory, or on
+// DefaultLocalDir when it is empty, creating the directory when missing.
+func NewLocal(dir string) (*Local, error) {
+	if dir == "" {
+		dir = DefaultLocalDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{root: dir}, nil
+}
+
+// Put writes the content to a temporary file renamed to the path of the key,
+// so a file is never read while partially written.
+func (l *Local) Put(key string, content io.Reader) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// the temporary files are only readable by their owner
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Open opens the file of the key for reading.
+func (l *Local) Open(key string) (io.ReadSeekCloser, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete removes the file of the key.
+func (l *Local) Delete(key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path returns the path of the file of the key on the filesystem.
+func (l *Local) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) || path.Clean(key) != key ||
+		key == ".." || strings.HasPrefix(key, "../") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,30 @@
+// Package storage keeps the files uploaded to the API, such as the images of
+// the cakes, behind the Storage interface, so they can be moved from the local
+// filesystem to another backend without touching the controllers.
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no file is stored under the key
+var ErrNotFound = errors.New("storage: file not found")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or escape the
+// storage with ".." elements
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Storage stores files under slash separated keys, such as
+// "cakes/1/image.jpg". The stored files are never changed in place: a new
+// version of a file is put under a new key.
+type Storage interface {
+	// Put stores the content under the key, replacing the file stored under it
+	Put(key string, content io.Reader) error
+	// Open opens the file stored under the key, returning ErrNotFound when
+	// there is none
+	Open(key string) (io.ReadSeekCloser, error)
+	// Delete removes the file stored under the key, and does nothing when
+	// there is none
+	Delete(key string) error
+}
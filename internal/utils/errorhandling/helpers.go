@@ -1,35 +1,261 @@
 package errorhandling
 
 import (
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 
+	"github.com/LeandroDeJesus-S/confectionery/internal/i18n"
 	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
 	"github.com/LeandroDeJesus-S/confectionery/internal/utils/httphelpers"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/jsonschema"
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/patch"
+	"github.com/go-playground/validator/v10"
 )
 
+// ProblemContentType is the media type of the error responses
+const ProblemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the type URI of every problem returned by the API
+const problemTypeBase = "urn:confectionery:problem:"
+
+// ValidationProblemType identifies the problems carrying field-level errors
+const ValidationProblemType = problemTypeBase + "validation-error"
+
+// ProblemType returns the stable type URI of the problems with the given
+// HTTP status, such as "urn:confectionery:problem:not-found".
+func ProblemType(status int) string {
+	slug := strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "-"))
+	if slug == "" {
+		slug = "error"
+	}
+	return problemTypeBase + slug
+}
+
+// ProblemResponse writes an application/problem+json response with the given
+// status code and detail message, referencing the request ID of the response.
+func ProblemResponse(w http.ResponseWriter, status int, detail string) {
+	WriteProblem(w, schemas.Problem{
+		Type:   ProblemType(status),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+}
+
+// WriteProblem writes the given problem as an application/problem+json
+// response, whatever the Accept header of the request, filling the request ID
+// from the response headers and translating the title and detail to the
+// language announced on Content-Language.
+func WriteProblem(w http.ResponseWriter, problem schemas.Problem) {
+	problem = translate(w, problem)
+	problem.RequestID = w.Header().Get(httphelpers.RequestIDHeader)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Println("Cannot write the problem", err)
+	}
+}
+
 // CheckOrHttpError checks if an error is not nil and writes an appropriate error message to the
 // http.ResponseWriter with the given response code. If the error is nil, the function returns true.
 // If the error is not nil, the function returns false.
 //
-// If the error is not nil and the msg parameter is not nil, it will write a JSON response with
-// the given messages and the given response code.
+// If the error is not nil and the msg parameter is not nil, it will write a problem response with
+// the given messages as detail and the given response code.
 //
-// If the error is not nil and the msg parameter is nil, it will write a JSON response with the
-// error message and the given response code.
+// If the error is not nil and the msg parameter is nil, it will write a problem response with the
+// error message as detail and the given response code.
 func CheckOrHttpError(err error, w http.ResponseWriter, respCode int, msg ...string) bool {
 	if err != nil {
 		if msg != nil {
-			messages := schemas.Message{Code: respCode, Detail: msg}
-			httphelpers.JsonResponse(w, respCode, messages)
+			ProblemResponse(w, respCode, strings.Join(msg, "; "))
 			return false
 		}
 
-		out := schemas.Message{
-			Code:    respCode,
-			Detail: []string{err.Error()},
-		}
-		httphelpers.JsonResponse(w, respCode, out)
+		ProblemResponse(w, respCode, err.Error())
 		return false
 	}
 	return true
 }
+
+// CheckValidationError checks the error returned by validator.Validate. If it
+// is not nil, a 400 Bad Request problem is written listing every rejected
+// field by its JSON name, and the function returns false.
+func CheckValidationError(err error, w http.ResponseWriter) bool {
+	if err == nil {
+		return true
+	}
+
+	WriteProblem(w, validationProblem(w, err))
+	return false
+}
+
+// NewProblem returns the problem with the given status code and detail
+// message translated to the language of the response, for the problems
+// embedded in other responses, such as the item results of the bulk endpoints.
+func NewProblem(w http.ResponseWriter, status int, detail string) *schemas.Problem {
+	problem := translate(w, schemas.Problem{
+		Type:   ProblemType(status),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+	return &problem
+}
+
+// NewValidationProblem returns the problem written by CheckValidationError for
+// the given error, to be embedded in other responses like NewProblem.
+func NewValidationProblem(w http.ResponseWriter, err error) *schemas.Problem {
+	problem := translate(w, validationProblem(w, err))
+	return &problem
+}
+
+// CheckSchemaError checks whether the error is the jsonschema.ValidationError
+// of the JSON document held by the given field. If so, a 400 Bad Request
+// problem is written listing every rejected value by its path, such as
+// "personalization.message", and the function returns false.
+func CheckSchemaError(err error, field string, w http.ResponseWriter) bool {
+	problem, ok := schemaProblem(w, err, field)
+	if !ok {
+		return true
+	}
+
+	WriteProblem(w, problem)
+	return false
+}
+
+// NewSchemaProblem returns the problem written by CheckSchemaError for the
+// given error, to be embedded in other responses like NewProblem, or nil if
+// the error is not a jsonschema.ValidationError.
+func NewSchemaProblem(w http.ResponseWriter, err error, field string) *schemas.Problem {
+	problem, ok := schemaProblem(w, err, field)
+	if !ok {
+		return nil
+	}
+
+	problem = translate(w, problem)
+	return &problem
+}
+
+// CheckPatchError checks the error returned by patch.Decode. If it is not
+// nil, a 409 Conflict or 400 Bad Request problem
+// is written depending on the error, and the function returns false.
+func CheckPatchError(err error, w http.ResponseWriter) bool {
+	switch {
+	case err == nil:
+		return true
+
+	case errors.Is(err, patch.ErrTestFailed):
+		ProblemResponse(w, http.StatusConflict, "Patch test operation failed")
+
+	default:
+		ProblemResponse(w, http.StatusBadRequest, "Invalid input")
+	}
+	return false
+}
+
+// CheckIfMatch checks the If-Match header of the request against the current
+// entity tag of the resource. If the precondition fails, a 412 Precondition
+// Failed problem is written and the function returns false.
+func CheckIfMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if httphelpers.IfMatch(r, etag) {
+		return true
+	}
+
+	ProblemResponse(w, http.StatusPreconditionFailed, "The resource was modified by another request")
+	return false
+}
+
+// validationProblem builds the problem listing every field rejected by
+// validator.Validate by its JSON name. Other errors are reported as an
+// invalid input.
+func validationProblem(w http.ResponseWriter, err error) schemas.Problem {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return schemas.Problem{
+			Type:   ProblemType(http.StatusBadRequest),
+			Title:  http.StatusText(http.StatusBadRequest),
+			Status: http.StatusBadRequest,
+			Detail: "Invalid input",
+		}
+	}
+
+	problem := schemas.Problem{
+		Type:   ValidationProblemType,
+		Title:  "Validation failed",
+		Status: http.StatusBadRequest,
+		Detail: "One or more fields are invalid",
+	}
+	lang := language(w)
+	for _, fieldErr := range validationErrs {
+		problem.Errors = append(problem.Errors, schemas.FieldError{
+			Field:   fieldPath(fieldErr),
+			Rule:    fieldErr.Tag(),
+			Message: i18n.TranslateFieldError(lang, fieldErr),
+		})
+	}
+	return problem
+}
+
+// schemaProblem builds the problem listing every value of the JSON document
+// of the field rejected by its JSON Schema, reporting whether the error is a
+// jsonschema.ValidationError.
+func schemaProblem(w http.ResponseWriter, err error, field string) (schemas.Problem, bool) {
+	var schemaErrs jsonschema.ValidationError
+	if !errors.As(err, &schemaErrs) {
+		return schemas.Problem{}, false
+	}
+
+	problem := schemas.Problem{
+		Type:   ValidationProblemType,
+		Title:  "Validation failed",
+		Status: http.StatusBadRequest,
+		Detail: "One or more fields are invalid",
+	}
+	lang := language(w)
+	for _, schemaErr := range schemaErrs {
+		path := field
+		switch {
+		case strings.HasPrefix(schemaErr.Path, "["):
+			path += schemaErr.Path
+		case schemaErr.Path != "":
+			path += "." + schemaErr.Path
+		}
+		problem.Errors = append(problem.Errors, schemas.FieldError{
+			Field:   path,
+			Rule:    schemaErr.Keyword,
+			Message: i18n.TranslateSchemaError(lang, path, schemaErr),
+		})
+	}
+	return problem, true
+}
+
+// translate translates the title and detail of the problem to the language
+// of the response.
+func translate(w http.ResponseWriter, problem schemas.Problem) schemas.Problem {
+	lang := language(w)
+	problem.Title = i18n.T(lang, problem.Title)
+	problem.Detail = i18n.T(lang, problem.Detail)
+	return problem
+}
+
+// fieldPath returns the JSON path of the field without the root struct name,
+// e.g. "email" or "items[0].qtd".
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, found := strings.Cut(fieldErr.Namespace(), ".")
+	if !found {
+		return fieldErr.Field()
+	}
+	return path
+}
+
+// language returns the language negotiated for the response.
+func language(w http.ResponseWriter) string {
+	if lang := w.Header().Get("Content-Language"); lang != "" {
+		return lang
+	}
+	return i18n.DefaultLanguage()
+}
@@ -0,0 +1,77 @@
+package httphelpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag returns the strong entity tag of a resource at the given version,
+// which the If-Match header is checked against.
+func ETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// RepresentationETag returns the strong entity tag of a representation of a
+// resource at the given version: the version followed by a digest of the
+// body, so the representations with other fields, embedded resources or
+// media types, or embedding resources that changed, have other tags.
+func RepresentationETag(version uint, body []byte) string {
+	digest := sha256.Sum256(body)
+	return `"` + strconv.FormatUint(uint64(version), 10) + "-" + hex.EncodeToString(digest[:8]) + `"`
+}
+
+// NotModified sets the ETag header and checks the If-None-Match header of a
+// GET request against it. When one of the tags matches, a 304 Not Modified
+// response is written and the function returns true.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch == "" || !matchETag(ifNoneMatch, true, func(candidate string) bool {
+		return candidate == strings.TrimPrefix(etag, "W/")
+	}) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// IfMatch reports whether the If-Match header of the request, if any, is
+// satisfied by the current entity tag of the resource, returned by ETag.
+// The tags of its representations match it by their version.
+func IfMatch(r *http.Request, etag string) bool {
+	ifMatch := r.Header.Get("If-Match")
+	return ifMatch == "" || matchETag(ifMatch, false, func(candidate string) bool {
+		version, _, _ := strings.Cut(strings.Trim(candidate, `"`), "-")
+		return `"`+version+`"` == etag
+	})
+}
+
+// matchETag checks a list of entity tags, as sent on the conditional
+// headers, with the match function. Weak tags only match when weak
+// comparison is allowed, as for If-None-Match, and are given to match
+// without their W/ prefix.
+func matchETag(header string, weak bool, match func(candidate string) bool) bool {
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if match(candidate) {
+			return true
+		}
+	}
+	return false
+}
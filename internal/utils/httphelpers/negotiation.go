@@ -0,0 +1,349 @@
+package httphelpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/utils/spreadsheet"
+)
+
+// Media types negotiated by Respond
+const (
+	JSONContentType = "application/json"
+	CSVContentType  = spreadsheet.CSV
+	XMLContentType  = "application/xml"
+)
+
+// ErrNotAcceptable is returned by Negotiate when the Accept header admits
+// none of the media types of the API.
+var ErrNotAcceptable = errors.New("not acceptable")
+
+// Negotiate returns the media type of the response, JSON, CSV or XML, chosen
+// from the Accept header of the request. JSON is chosen when the header is
+// missing or accepts any type, and CSV or XML only when they are named and
+// preferred over JSON and the types the API does not serve, so browsers,
+// which prefer HTML, get JSON.
+func Negotiate(r *http.Request) (string, error) {
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return JSONContentType, nil
+	}
+
+	type acceptedType struct {
+		mediaType string
+		quality   float64
+	}
+	var accepted []acceptedType
+	jsonQuality := 0.0
+	for value := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		accepted = append(accepted, acceptedType{mediaType, quality})
+		if negotiated(mediaType) == JSONContentType {
+			jsonQuality = max(jsonQuality, quality)
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].quality > accepted[j].quality })
+
+	for _, a := range accepted {
+		switch negotiated(a.mediaType) {
+		case CSVContentType, XMLContentType:
+			if a.quality > jsonQuality {
+				return negotiated(a.mediaType), nil
+			}
+			return JSONContentType, nil
+		default:
+			// JSON, or a type the API does not serve, such as HTML, which
+			// leaves the choice to the API when any type is accepted
+			if jsonQuality > 0 {
+				return JSONContentType, nil
+			}
+		}
+	}
+	return "", ErrNotAcceptable
+}
+
+// negotiated returns the media type served for the accepted one, or an empty
+// string when the API does not serve it.
+func negotiated(mediaType string) string {
+	switch mediaType {
+	case JSONContentType, "application/*", "*/*":
+		return JSONContentType
+	case CSVContentType:
+		return CSVContentType
+	case XMLContentType, "text/xml":
+		return XMLContentType
+	}
+	return ""
+}
+
+// filename names the CSV and XML responses after the path of the request,
+// such as "customers-1-orders" for /customers/1/orders.
+func filename(r *http.Request) string {
+	var segments []string
+	for segment := range strings.SplitSeq(r.URL.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return "data"
+	}
+	return strings.Join(segments, "-")
+}
+
+// Stream is a list of objects produced while the response is written, such
+// as records read from the database in batches, so long lists are never held
+// in memory at once.
+type Stream struct {
+	// Of is an empty object of the list, naming the columns of CSV responses
+	Of any
+	// Each calls yield with each object of the list, stopping at the first
+	// error returned
+	Each func(yield func(item any) error) error
+}
+
+// list returns the stream of the objects of the data, a Stream, a slice or a
+// single object, reporting whether the data is a list.
+func list(data any) (Stream, bool) {
+	if stream, ok := data.(Stream); ok {
+		return stream, true
+	}
+
+	value := reflect.ValueOf(data)
+	if value.Kind() != reflect.Slice || isObject(data) {
+		return Stream{Of: data, Each: func(yield func(item any) error) error {
+			return yield(data)
+		}}, false
+	}
+	return Stream{
+		Of: reflect.Zero(value.Type().Elem()).Interface(),
+		Each: func(yield func(item any) error) error {
+			for i := range value.Len() {
+				if err := yield(value.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}, true
+}
+
+// writeJSON writes the data as JSON, one object of a list at a time.
+func writeJSON(w io.Writer, data any) error {
+	stream, isList := list(data)
+	if !isList {
+		return json.NewEncoder(w).Encode(data)
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	separator := ""
+	err := stream.Each(func(item any) error {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, separator); err != nil {
+			return err
+		}
+		separator = ","
+		_, err = w.Write(encoded)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+// writeCSV writes a CSV row for each object of the data. The columns are
+// named after the fields of the empty object of the stream.
+func writeCSV(w io.Writer, data any) error {
+	writer, err := spreadsheet.NewWriter(CSVContentType, w)
+	if err != nil {
+		return err
+	}
+
+	stream, _ := list(data)
+	var header []string
+	values := spreadsheet.Values
+	if object, ok := stream.Of.(Object); ok {
+		for _, field := range object {
+			if field.Value != nil && !spreadsheet.IsResource(reflect.TypeOf(field.Value)) {
+				header = append(header, field.Name)
+			}
+		}
+		values = func(item any) []any {
+			row := make([]any, len(header))
+			for i, name := range header {
+				row[i] = spreadsheet.Cell(item.(Object).Get(name))
+			}
+			return row
+		}
+	} else {
+		header = spreadsheet.Header(stream.Of)
+	}
+
+	row := make([]any, len(header))
+	for i, name := range header {
+		row[i] = name
+	}
+	if err := writer.Write(row); err != nil {
+		return err
+	}
+	err = stream.Each(func(item any) error {
+		return writer.Write(values(item))
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// isObject reports whether the data is a single Object.
+func isObject(data any) bool {
+	_, ok := data.(Object)
+	return ok
+}
+
+// writeXML writes the data as XML. Lists are wrapped in an <items> element
+// with an <item> element for each object, and single objects are written as
+// an <item> element.
+func writeXML(w io.Writer, data any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+
+	stream, isList := list(data)
+	if isList {
+		if err := encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: "items"}}); err != nil {
+			return err
+		}
+	}
+	err := stream.Each(func(item any) error {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		decoder := json.NewDecoder(bytes.NewReader(encoded))
+		decoder.UseNumber()
+		if err := transcodeXML(encoder, decoder, "item"); err != nil {
+			return err
+		}
+		// send each object as soon as it is encoded
+		return encoder.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	if isList {
+		if err := encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "items"}}); err != nil {
+			return err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// transcodeXML reads the next JSON value of the decoder and writes it as an
+// XML element of the given name. The fields of objects become elements named
+// after them and the elements of arrays become <item> elements, keeping the
+// order of the JSON document. Fields whose names are not valid XML names,
+// such as the personalization keys chosen by the customers, are written as
+// <field> elements holding their names on the name attribute instead.
+func transcodeXML(encoder *xml.Encoder, decoder *json.Decoder, name string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !isXMLName(name) {
+		start = xml.StartElement{
+			Name: xml.Name{Local: "field"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: name}},
+		}
+	}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		for decoder.More() {
+			child := "item"
+			if token == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				child = key.(string)
+			}
+			if err := transcodeXML(encoder, decoder, child); err != nil {
+				return err
+			}
+		}
+		// the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+
+	case string:
+		err = encoder.EncodeToken(xml.CharData(token))
+	case json.Number:
+		err = encoder.EncodeToken(xml.CharData(token.String()))
+	case bool:
+		err = encoder.EncodeToken(xml.CharData(strconv.FormatBool(token)))
+	}
+	if err != nil {
+		return err
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// isXMLName reports whether the name is a valid XML element name, leaving
+// out the names with a namespace prefix and the ones reserved by starting
+// with "xml".
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.' || unicode.In(r, unicode.Mn, unicode.Mc)):
+		default:
+			return false
+		}
+	}
+	return true
+}
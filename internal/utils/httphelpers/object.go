@@ -0,0 +1,138 @@
+package httphelpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Field is a field of an Object, named after its JSON name
+type Field struct {
+	Name  string
+	Value any
+}
+
+// Object holds some of the fields of an output schema, such as the ones
+// requested with ?fields=. Its fields are encoded in the order they are
+// held, with the values of their original types.
+type Object []Field
+
+// SelectFields returns the fields of the struct, or pointer to a struct,
+// named by keep, in the order they are declared and flattening the embedded
+// structs. Fields tagged with omitempty or omitzero are left out when they
+// would be omitted from the JSON encoding of the struct, unless all is true,
+// which lists every field named by keep, such as for the columns of a CSV
+// header.
+func SelectFields(v any, keep []string, all bool) Object {
+	object := Object{}
+	selectFields(reflect.ValueOf(v), keep, all, &object)
+	return object
+}
+
+func selectFields(value reflect.Value, keep []string, all bool, object *Object) {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	t := value.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if field.Anonymous && name == "" {
+			if all && fieldValue.Kind() == reflect.Pointer && fieldValue.IsNil() {
+				fieldValue = reflect.New(field.Type.Elem())
+			}
+			selectFields(fieldValue, keep, all, object)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if !slices.Contains(keep, name) || (!all && omitted(fieldValue, options)) {
+			continue
+		}
+		*object = append(*object, Field{Name: name, Value: fieldValue.Interface()})
+	}
+}
+
+// omitted reports whether the value is left out of the JSON encoding with
+// the options of its tag.
+func omitted(value reflect.Value, options string) bool {
+	for option := range strings.SplitSeq(options, ",") {
+		switch option {
+		case "omitzero":
+			if value.IsZero() {
+				return true
+			}
+		case "omitempty":
+			switch value.Kind() {
+			case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+				if value.Len() == 0 {
+					return true
+				}
+			case reflect.Pointer, reflect.Interface:
+				if value.IsNil() {
+					return true
+				}
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+				reflect.Float32, reflect.Float64:
+				if value.IsZero() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// MarshalJSON encodes the object with its fields in order.
+func (o Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Get returns the value of the named field, or nil when the object does not
+// hold it.
+func (o Object) Get(name string) any {
+	for _, field := range o {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return nil
+}
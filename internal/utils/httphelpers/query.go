@@ -0,0 +1,32 @@
+package httphelpers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// QueryList returns the comma separated values of a query parameter, such as
+// ?include=customer,cake, which may also be repeated. Empty values are
+// skipped.
+func QueryList(r *http.Request, name string) []string {
+	var values []string
+	for _, param := range r.URL.Query()[name] {
+		for value := range strings.SplitSeq(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// QueryBool parses a boolean query parameter, such as ?includeDeleted=true,
+// returning false when it is absent.
+func QueryBool(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
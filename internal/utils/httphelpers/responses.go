@@ -1,16 +1,113 @@
 package httphelpers
 
 import (
-	"encoding/json"
+	"bytes"
+	"io"
+	"log"
+	"mime"
 	"net/http"
 )
 
-// JsonResponse writes a JSON response to the HTTP response writer
-// with the given response code and data.
-func JsonResponse(w http.ResponseWriter, respCode int, data any) {
-	w.WriteHeader(respCode)
-	err := json.NewEncoder(w).Encode(data)
+// RequestIDHeader is the header carrying the identifier of the request,
+// echoed back on every response.
+const RequestIDHeader = "X-Request-ID"
+
+// Respond writes the data, an object, a list of objects or a Stream, with
+// the given status code in the media type negotiated from the Accept header:
+// JSON, CSV or XML. Lists are written one object at a time instead of
+// encoding the whole list at once, and nil data writes no body.
+//
+// CSV responses have a row for each object, with a column for each field
+// not holding other resources, the lists and documents as JSON text, and XML
+// responses have an element for each field, named after its JSON name, in
+// the order of the output schema. CSV and XML responses are named after the
+// path of the request on Content-Disposition.
+//
+// If the Accept header of a GET request admits none of the media types,
+// ErrNotAcceptable is returned and nothing is written, so the caller can
+// respond with a problem. The other requests already made their changes, so
+// they are answered in JSON instead.
+func Respond(w http.ResponseWriter, r *http.Request, status int, data any) error {
+	w.Header().Add("Vary", "Accept")
+
+	mediaType, err := Negotiate(r)
+	if err != nil {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			return err
+		}
+		mediaType = JSONContentType
+	}
+
+	if data == nil {
+		w.WriteHeader(status)
+		return nil
+	}
+
+	setContentType(w, r, mediaType)
+	w.WriteHeader(status)
+	if err := encode(w, mediaType, data); err != nil {
+		// the status was already sent, so the error can only be logged
+		log.Println("Cannot write the response", err)
+	}
+	return nil
+}
+
+// RespondVersion writes the data of a resource at the given version like
+// Respond, with a 200 OK status code. The body is encoded upfront and tagged
+// with RepresentationETag, and a 304 Not Modified response is written
+// instead when the tag matches the If-None-Match header. ErrNotAcceptable and
+// the encoding errors are returned before anything is written.
+func RespondVersion(w http.ResponseWriter, r *http.Request, version uint, data any) error {
+	w.Header().Add("Vary", "Accept")
+
+	mediaType, err := Negotiate(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+
+	var body bytes.Buffer
+	if err := encode(&body, mediaType, data); err != nil {
+		return err
+	}
+	if NotModified(w, r, RepresentationETag(version, body.Bytes())) {
+		return nil
+	}
+
+	setContentType(w, r, mediaType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		log.Println("Cannot write the response", err)
+	}
+	return nil
+}
+
+// setContentType sets the Content-Type of the media type, naming the CSV
+// and XML files after the path of the request on Content-Disposition.
+func setContentType(w http.ResponseWriter, r *http.Request, mediaType string) {
+	if mediaType == JSONContentType {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", JSONContentType)
+		}
+	} else {
+		extension := "csv"
+		if mediaType == XMLContentType {
+			extension = "xml"
+		}
+		w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(
+			"attachment", map[string]string{"filename": filename(r) + "." + extension},
+		))
+	}
+}
+
+// encode writes the data in the media type.
+func encode(w io.Writer, mediaType string, data any) error {
+	switch mediaType {
+	case CSVContentType:
+		return writeCSV(w, data)
+	case XMLContentType:
+		return writeXML(w, data)
+	default:
+		return writeJSON(w, data)
 	}
 }
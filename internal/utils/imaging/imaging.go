@@ -0,0 +1,90 @@
+// Package imaging scales down the images uploaded to the API into thumbnails,
+// using only the image packages of the standard library.
+package imaging
+
+import (
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	// registers the GIF decoder on image.Decode
+	_ "image/gif"
+)
+
+// jpegQuality is the quality of the JPEG thumbnails
+const jpegQuality = 85
+
+// Decode decodes a JPEG, PNG or GIF image, taking the first frame of
+// animated GIFs.
+func Decode(r io.Reader) (image.Image, error) {
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// Thumbnail scales the image down to fit a square of the given size, keeping
+// its aspect ratio. Each pixel of the thumbnail is the average of the pixels
+// it covers on the image, which keeps the details smooth on large reductions.
+// Images already fitting the square are only copied.
+//
+// The image is converted to RGBA one band of rows at a time, the ones covered
+// by a row of the thumbnail, so it is never copied whole.
+func Thumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if sw <= size && sh <= size {
+		dst := image.NewRGBA(image.Rect(0, 0, sw, sh))
+		draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+		return dst
+	}
+
+	dw, dh := size, size
+	if sw > sh {
+		dh = max(1, (sh*size+sw/2)/sw)
+	} else {
+		dw = max(1, (sw*size+sh/2)/sh)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	band := image.NewRGBA(image.Rect(0, 0, sw, (sh+dh-1)/dh))
+	for y := range dh {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		rows := image.Rect(0, 0, sw, y1-y0)
+		draw.Draw(band, rows, img, image.Pt(bounds.Min.X, bounds.Min.Y+y0), draw.Src)
+
+		for x := range dw {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+
+			// the pixels are premultiplied by their alpha, so they are
+			// averaged directly
+			var sum [4]uint64
+			for by := range y1 - y0 {
+				row := band.Pix[band.PixOffset(x0, by):band.PixOffset(x1, by)]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += uint64(row[i])
+					sum[1] += uint64(row[i+1])
+					sum[2] += uint64(row[i+2])
+					sum[3] += uint64(row[i+3])
+				}
+			}
+
+			count := uint64((x1 - x0) * (y1 - y0))
+			pixel := dst.Pix[dst.PixOffset(x, y):]
+			for i := range sum {
+				pixel[i] = uint8((sum[i] + count/2) / count)
+			}
+		}
+	}
+	return dst
+}
+
+// Encode writes the image as JPEG when the source was a JPEG, which has no
+// transparency to keep, and as PNG otherwise, returning the media type and
+// the extension of the format written.
+func Encode(w io.Writer, img image.Image, sourceType string) (mediaType, extension string, err error) {
+	if sourceType == "image/jpeg" {
+		return "image/jpeg", ".jpg", jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	return "image/png", ".png", png.Encode(w, img)
+}
@@ -0,0 +1,315 @@
+// Package jsonschema validates JSON documents against the subset of JSON
+// Schema used by the API to describe the personalization of the cakes:
+// the type, enum, string, number and array constraints, and objects with
+// properties, required and additionalProperties. Schemas using any other
+// keyword are rejected by Parse instead of being partially enforced.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Types lists the values accepted by the type keyword
+var Types = []string{"object", "array", "string", "integer", "number", "boolean"}
+
+// Formats lists the values accepted by the format keyword of strings
+var Formats = []string{"date", "date-time", "email", "uri"}
+
+// Schema is a parsed JSON Schema. The zero value accepts any document.
+type Schema struct {
+	Dialect     string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Enum        []any  `json:"enum,omitempty"`
+
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	Format    string `json:"format,omitempty"`
+
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+
+	Items    *Schema `json:"items,omitempty"`
+	MinItems *int    `json:"minItems,omitempty"`
+	MaxItems *int    `json:"maxItems,omitempty"`
+
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// AdditionalProperties rejects the properties missing from Properties
+	// when false
+	AdditionalProperties *bool `json:"additionalProperties,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Parse decodes and checks a JSON Schema, returning an error naming the
+// first unsupported or inconsistent keyword.
+func Parse(data []byte) (*Schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var schema Schema
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("jsonschema: unexpected data after the schema")
+	}
+	if err := schema.check(""); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// check checks the keywords of the schema found at the given path and
+// compiles its pattern.
+func (s *Schema) check(path string) error {
+	invalid := func(keyword, reason string) error {
+		return fmt.Errorf("jsonschema: %s%s %s", path, keyword, reason)
+	}
+
+	if s.Type != "" && !slices.Contains(Types, s.Type) {
+		return invalid("type", "must be one of "+strings.Join(Types, ", "))
+	}
+	if s.Format != "" && !slices.Contains(Formats, s.Format) {
+		return invalid("format", "must be one of "+strings.Join(Formats, ", "))
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return invalid("pattern", "must be a valid regular expression")
+		}
+		s.pattern = pattern
+	}
+
+	for _, limit := range []struct {
+		keyword string
+		value   *int
+	}{
+		{"minLength", s.MinLength}, {"maxLength", s.MaxLength}, {"minItems", s.MinItems}, {"maxItems", s.MaxItems},
+	} {
+		if limit.value != nil && *limit.value < 0 {
+			return invalid(limit.keyword, "must not be negative")
+		}
+	}
+	if s.MinLength != nil && s.MaxLength != nil && *s.MinLength > *s.MaxLength {
+		return invalid("maxLength", "must not be less than minLength")
+	}
+	if s.MinItems != nil && s.MaxItems != nil && *s.MinItems > *s.MaxItems {
+		return invalid("maxItems", "must not be less than minItems")
+	}
+	if s.Minimum != nil && s.Maximum != nil && *s.Minimum > *s.Maximum {
+		return invalid("maximum", "must not be less than minimum")
+	}
+
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; !ok {
+			return invalid("required", "lists "+strconv.Quote(name)+", which is not a property")
+		}
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return invalid("properties", "must hold a schema for "+strconv.Quote(name))
+		}
+		if err := property.check(path + "properties." + name + "."); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check(path + "items.")
+	}
+	return nil
+}
+
+// Error reports a value rejected by a keyword of its schema. Path is the
+// JSON path of the value, empty for the root, and Param the value of the
+// keyword, such as the maximum length or the expected type.
+type Error struct {
+	Path    string
+	Keyword string
+	Param   string
+}
+
+func (e Error) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "the document"
+	}
+	if e.Param == "" {
+		return fmt.Sprintf("%s fails the %s rule", path, e.Keyword)
+	}
+	return fmt.Sprintf("%s fails the %s %s rule", path, e.Keyword, e.Param)
+}
+
+// ValidationError lists every value of a document rejected by its schema.
+type ValidationError []Error
+
+func (e ValidationError) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return "jsonschema: " + strings.Join(messages, "; ")
+}
+
+// Validate validates the JSON document against the schema, returning a
+// ValidationError listing every rejected value, or nil.
+func (s *Schema) Validate(data []byte) error {
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("jsonschema: %w", err)
+	}
+
+	var errs ValidationError
+	s.validate("", document, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate appends to errs the errors of the value at the given path.
+func (s *Schema) validate(path string, value any, errs *ValidationError) {
+	fail := func(keyword, param string) {
+		*errs = append(*errs, Error{Path: path, Keyword: keyword, Param: param})
+	}
+
+	if s.Type != "" && !hasType(value, s.Type) {
+		fail("type", s.Type)
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(option any) bool { return equal(option, value) }) {
+		options := make([]string, 0, len(s.Enum))
+		for _, option := range s.Enum {
+			encoded, _ := json.Marshal(option)
+			options = append(options, string(encoded))
+		}
+		fail("enum", strings.Join(options, ", "))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("minLength", strconv.Itoa(*s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("maxLength", strconv.Itoa(*s.MaxLength))
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("pattern", s.Pattern)
+		}
+		if s.Format != "" && !hasFormat(v, s.Format) {
+			fail("format", s.Format)
+		}
+
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("minimum", strconv.FormatFloat(*s.Minimum, 'f', -1, 64))
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("maximum", strconv.FormatFloat(*s.Maximum, 'f', -1, 64))
+		}
+
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("minItems", strconv.Itoa(*s.MinItems))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("maxItems", strconv.Itoa(*s.MaxItems))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(path+"["+strconv.Itoa(i)+"]", item, errs)
+			}
+		}
+
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, Error{Path: join(path, name), Keyword: "required"})
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			switch {
+			case ok:
+				property.validate(join(path, name), v[name], errs)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				*errs = append(*errs, Error{Path: join(path, name), Keyword: "additionalProperties"})
+			}
+		}
+	}
+}
+
+// join returns the path of the property of the object at the given path.
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// hasType reports whether the decoded JSON value is of the given type.
+func hasType(value any, typ string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		return typ == "object"
+	case []any:
+		return typ == "array"
+	case string:
+		return typ == "string"
+	case bool:
+		return typ == "boolean"
+	case float64:
+		return typ == "number" || (typ == "integer" && v == math.Trunc(v))
+	default:
+		return false
+	}
+}
+
+// hasFormat reports whether the string is in the given format.
+func hasFormat(value, format string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "uri":
+		parsed, err := url.Parse(value)
+		return err == nil && parsed.Scheme != "" && parsed.Host != ""
+	default:
+		return true
+	}
+}
+
+// equal reports whether two decoded JSON values are equal.
+func equal(a, b any) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
@@ -0,0 +1,129 @@
+package jsonschema
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		// err is a part of the error message, empty when the schema is valid
+		err string
+	}{
+		{"empty", `{}`, ""},
+		{"object", `{"type":"object","properties":{"message":{"type":"string","maxLength":40}},"required":["message"],"additionalProperties":false}`, ""},
+		{"array", `{"type":"array","items":{"type":"string","enum":["red","blue"]},"minItems":1,"maxItems":3}`, ""},
+		{"formats", `{"type":"object","properties":{"day":{"type":"string","format":"date"},"site":{"type":"string","format":"uri"}}}`, ""},
+		{"unknown keyword", `{"type":"object","oneOf":[]}`, "oneOf"},
+		{"unknown type", `{"type":"null"}`, "type must be one of"},
+		{"unknown format", `{"type":"string","format":"ipv4"}`, "format must be one of"},
+		{"invalid pattern", `{"type":"string","pattern":"("}`, "pattern must be a valid regular expression"},
+		{"negative length", `{"type":"string","minLength":-1}`, "minLength must not be negative"},
+		{"inverted length", `{"type":"string","minLength":5,"maxLength":2}`, "maxLength must not be less than minLength"},
+		{"inverted items", `{"type":"array","minItems":5,"maxItems":2}`, "maxItems must not be less than minItems"},
+		{"inverted range", `{"type":"number","minimum":5,"maximum":2}`, "maximum must not be less than minimum"},
+		{"required without property", `{"type":"object","required":["name"]}`, `required lists "name"`},
+		{"null property", `{"type":"object","properties":{"name":null}}`, `properties must hold a schema for "name"`},
+		{"nested error path", `{"type":"object","properties":{"tags":{"type":"array","items":{"type":"text"}}}}`, "properties.tags.items.type"},
+		{"trailing data", `{} {}`, "unexpected data after the schema"},
+		{"not an object", `[]`, "jsonschema:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.schema))
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Parse() error = %v, want nil", err)
+			case tt.err != "" && err == nil:
+				t.Errorf("Parse() error = nil, want %q", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Errorf("Parse() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	const personalization = `{
+		"type": "object",
+		"properties": {
+			"message": {"type": "string", "minLength": 1, "maxLength": 10, "pattern": "^[A-Za-z ]*$"},
+			"candles": {"type": "integer", "minimum": 0, "maximum": 9},
+			"color": {"enum": ["pink", "white"]},
+			"toppings": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"deliverOn": {"type": "string", "format": "date"},
+			"contact": {"type": "string", "format": "email"}
+		},
+		"required": ["message"],
+		"additionalProperties": false
+	}`
+
+	tests := []struct {
+		name     string
+		schema   string
+		document string
+		want     []Error
+	}{
+		{"any document", `{}`, `[1, "a", null]`, nil},
+		{"valid", personalization, `{"message":"Happy day","candles":3,"color":"pink","toppings":["nuts"],"deliverOn":"2025-12-24","contact":"ana@example.com"}`, nil},
+		{"integer written as float", personalization, `{"message":"Hi","candles":3.0}`, nil},
+		{"wrong type", personalization, `["message"]`, []Error{{Path: "", Keyword: "type", Param: "object"}}},
+		{"missing required", personalization, `{"candles":1}`, []Error{{Path: "message", Keyword: "required"}}},
+		{"additional property", personalization, `{"message":"Hi","name":"Ana"}`, []Error{{Path: "name", Keyword: "additionalProperties"}}},
+		{"string limits", personalization, `{"message":"Happy birthday"}`, []Error{{Path: "message", Keyword: "maxLength", Param: "10"}}},
+		{"empty string", personalization, `{"message":""}`, []Error{{Path: "message", Keyword: "minLength", Param: "1"}}},
+		{"length in characters", `{"type":"string","maxLength":3}`, `"ção"`, nil},
+		{"pattern", personalization, `{"message":"Hi 2"}`, []Error{{Path: "message", Keyword: "pattern", Param: "^[A-Za-z ]*$"}}},
+		{"fraction for integer", personalization, `{"message":"Hi","candles":1.5}`, []Error{{Path: "candles", Keyword: "type", Param: "integer"}}},
+		{"number range", personalization, `{"message":"Hi","candles":10}`, []Error{{Path: "candles", Keyword: "maximum", Param: "9"}}},
+		{"enum", personalization, `{"message":"Hi","color":"blue"}`, []Error{{Path: "color", Keyword: "enum", Param: `"pink", "white"`}}},
+		{"array items", personalization, `{"message":"Hi","toppings":["nuts",1,true]}`, []Error{
+			{Path: "toppings", Keyword: "maxItems", Param: "2"},
+			{Path: "toppings[1]", Keyword: "type", Param: "string"},
+			{Path: "toppings[2]", Keyword: "type", Param: "string"},
+		}},
+		{"formats", personalization, `{"message":"Hi","deliverOn":"24/12/2025","contact":"Ana <ana@example.com>"}`, []Error{
+			{Path: "contact", Keyword: "format", Param: "email"},
+			{Path: "deliverOn", Keyword: "format", Param: "date"},
+		}},
+		{"uri format", `{"type":"string","format":"uri"}`, `"example.com"`, []Error{{Path: "", Keyword: "format", Param: "uri"}}},
+		{"date-time format", `{"type":"string","format":"date-time"}`, `"2025-12-24T10:00:00-03:00"`, nil},
+		{"nested objects", `{"type":"object","properties":{"box":{"type":"object","properties":{"size":{"type":"number","minimum":10}}}}}`, `{"box":{"size":5}}`, []Error{{Path: "box.size", Keyword: "minimum", Param: "10"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Parse([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			err = schema.Validate([]byte(tt.document))
+			var got ValidationError
+			if err != nil && !errors.As(err, &got) {
+				t.Fatalf("Validate() error = %v, want a ValidationError", err)
+			}
+			if !reflect.DeepEqual([]Error(got), tt.want) {
+				t.Errorf("Validate() errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidJSON(t *testing.T) {
+	schema, err := Parse([]byte(`{"type":"object"}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	err = schema.Validate([]byte(`{"message":`))
+	var validationErr ValidationError
+	if err == nil || errors.As(err, &validationErr) {
+		t.Errorf("Validate() error = %v, want a decoding error", err)
+	}
+}
@@ -0,0 +1,269 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrTestFailed is returned when a "test" operation of a JSON Patch does
+// not match the document.
+var ErrTestFailed = errors.New("json patch test operation failed")
+
+// Operation is a single operation of a JSON Patch document (RFC 6902).
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyJSONPatch applies the operations in order to the given document,
+// returning the patched copy. The patch is atomic: the original document is
+// never modified, even when an operation fails.
+func ApplyJSONPatch(doc map[string]any, ops []Operation) (map[string]any, error) {
+	var root any = deepCopy(doc)
+
+	for i, op := range ops {
+		var err error
+		root, err = applyOperation(root, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	patched, ok := root.(map[string]any)
+	if !ok {
+		return nil, errors.New("patched document is not an object")
+	}
+	return patched, nil
+}
+
+func applyOperation(root any, op Operation) (any, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		var value any
+		if len(op.Value) == 0 {
+			return nil, errors.New("missing value")
+		}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, err
+		}
+
+		if op.Op == "test" {
+			current, err := get(root, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, ErrTestFailed
+			}
+			return root, nil
+		}
+		return set(root, op.Path, value, op.Op == "replace")
+
+	case "remove":
+		root, _, err := remove(root, op.Path)
+		return root, err
+
+	case "move":
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		root, value, err := remove(root, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return set(root, op.Path, value, false)
+
+	case "copy":
+		value, err := get(root, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return set(root, op.Path, deepCopy(value), false)
+
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(root any, pointer string) (any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := root
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q not found", pointer)
+			}
+			current = value
+
+		case []any:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+
+		default:
+			return nil, fmt.Errorf("path %q not found", pointer)
+		}
+	}
+	return current, nil
+}
+
+// set adds or replaces the value at the pointer, returning the new root.
+// When mustExist is true the target must already exist, as required by
+// the "replace" operation.
+func set(root any, pointer string, value any, mustExist bool) (any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(root, joinPointer(tokens[:len(tokens)-1]))
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		if _, ok := node[last]; mustExist && !ok {
+			return nil, fmt.Errorf("path %q not found", pointer)
+		}
+		node[last] = value
+		return root, nil
+
+	case []any:
+		if mustExist {
+			index, err := arrayIndex(last, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			node[index] = value
+			return root, nil
+		}
+
+		index := len(node)
+		if last != "-" {
+			if index, err = arrayIndex(last, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		grown := append(node[:index:index], append([]any{value}, node[index:]...)...)
+		return set(root, joinPointer(tokens[:len(tokens)-1]), grown, true)
+
+	default:
+		return nil, fmt.Errorf("path %q not found", pointer)
+	}
+}
+
+// remove deletes the value at the pointer, returning the new root and the
+// removed value.
+func remove(root any, pointer string) (any, any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+
+	parentPointer := joinPointer(tokens[:len(tokens)-1])
+	parent, err := get(root, parentPointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %q not found", pointer)
+		}
+		delete(node, last)
+		return root, value, nil
+
+	case []any:
+		index, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[index]
+		shrunk := append(node[:index:index], node[index+1:]...)
+		root, err = set(root, parentPointer, shrunk, true)
+		return root, value, err
+
+	default:
+		return nil, nil, fmt.Errorf("path %q not found", pointer)
+	}
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return index, nil
+}
+
+func joinPointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		token = strings.ReplaceAll(token, "~", "~0")
+		b.WriteString("/" + strings.ReplaceAll(token, "/", "~1"))
+	}
+	return b.String()
+}
+
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = deepCopy(item)
+		}
+		return out
+
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = deepCopy(item)
+		}
+		return out
+
+	default:
+		return v
+	}
+}
@@ -0,0 +1,100 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	const doc = `{"name":"Bolo","tags":["chocolate","festa"],"nutrition":{"calories":350},"a/b":1,"m~n":2}`
+
+	tests := []struct {
+		name string
+		ops  string
+		// want is the patched document, empty when the patch fails
+		want string
+		// err is the error wrapped by the failure, if any in particular
+		err error
+	}{
+		{"no operations", `[]`, doc, nil},
+		{"add field", `[{"op":"add","path":"/price","value":4500}]`,
+			`{"name":"Bolo","tags":["chocolate","festa"],"nutrition":{"calories":350},"a/b":1,"m~n":2,"price":4500}`, nil},
+		{"add replaces field", `[{"op":"add","path":"/name","value":"Torta"}]`,
+			`{"name":"Torta","tags":["chocolate","festa"],"nutrition":{"calories":350},"a/b":1,"m~n":2}`, nil},
+		{"add to array", `[{"op":"add","path":"/tags/1","value":"vegano"}]`,
+			`{"name":"Bolo","tags":["chocolate","vegano","festa"],"nutrition":{"calories":350},"a/b":1,"m~n":2}`, nil},
+		{"append to array", `[{"op":"add","path":"/tags/-","value":"vegano"}]`,
+			`{"name":"Bolo","tags":["chocolate","festa","vegano"],"nutrition":{"calories":350},"a/b":1,"m~n":2}`, nil},
+		{"add nested field", `[{"op":"add","path":"/nutrition/sugar","value":20}]`,
+			`{"name":"Bolo","tags":["chocolate","festa"],"nutrition":{"calories":350,"sugar":20},"a/b":1,"m~n":2}`, nil},
+		{"escaped pointers", `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`,
+			`{"name":"Bolo","tags":["chocolate","festa"],"nutrition":{"calories":350},"a/b":3}`, nil},
+		{"remove array item", `[{"op":"remove","path":"/tags/0"}]`,
+			`{"name":"Bolo","tags":["festa"],"nutrition":{"calories":350},"a/b":1,"m~n":2}`, nil},
+		{"replace array item", `[{"op":"replace","path":"/tags/1","value":"aniversário"}]`,
+			`{"name":"Bolo","tags":["chocolate","aniversário"],"nutrition":{"calories":350},"a/b":1,"m~n":2}`, nil},
+		{"move", `[{"op":"move","from":"/tags/0","path":"/name"}]`,
+			`{"name":"chocolate","tags":["festa"],"nutrition":{"calories":350},"a/b":1,"m~n":2}`, nil},
+		{"copy", `[{"op":"copy","from":"/nutrition","path":"/copied"},{"op":"replace","path":"/copied/calories","value":1}]`,
+			`{"name":"Bolo","tags":["chocolate","festa"],"nutrition":{"calories":350},"copied":{"calories":1},"a/b":1,"m~n":2}`, nil},
+		{"test", `[{"op":"test","path":"/tags","value":["chocolate","festa"]},{"op":"remove","path":"/tags"}]`,
+			`{"name":"Bolo","nutrition":{"calories":350},"a/b":1,"m~n":2}`, nil},
+		{"failed test", `[{"op":"remove","path":"/tags"},{"op":"test","path":"/name","value":"Torta"}]`, "", ErrTestFailed},
+		{"replace missing field", `[{"op":"replace","path":"/price","value":1}]`, "", nil},
+		{"remove missing field", `[{"op":"remove","path":"/price"}]`, "", nil},
+		{"add past the end", `[{"op":"add","path":"/tags/3","value":"x"}]`, "", nil},
+		{"leading zero index", `[{"op":"remove","path":"/tags/01"}]`, "", nil},
+		{"add to missing parent", `[{"op":"add","path":"/missing/field","value":1}]`, "", nil},
+		{"move into child", `[{"op":"move","from":"/nutrition","path":"/nutrition/inner"}]`, "", nil},
+		{"missing value", `[{"op":"add","path":"/price"}]`, "", nil},
+		{"invalid pointer", `[{"op":"add","path":"price","value":1}]`, "", nil},
+		{"unknown operation", `[{"op":"merge","path":"/price","value":1}]`, "", nil},
+		{"remove document", `[{"op":"remove","path":""}]`, "", nil},
+		{"replace document", `[{"op":"replace","path":"","value":[]}]`, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var original map[string]any
+			if err := json.Unmarshal([]byte(doc), &original); err != nil {
+				t.Fatal(err)
+			}
+			var ops []Operation
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ApplyJSONPatch(original, ops)
+			if tt.want == "" {
+				switch {
+				case err == nil:
+					t.Errorf("ApplyJSONPatch() = %v, want an error", got)
+				case tt.err != nil && !errors.Is(err, tt.err):
+					t.Errorf("ApplyJSONPatch() error = %v, want %v", err, tt.err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("ApplyJSONPatch() error = %v", err)
+				}
+				var want map[string]any
+				if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("ApplyJSONPatch() = %v, want %v", got, want)
+				}
+			}
+
+			// the patch is atomic, leaving the original document untouched
+			var unchanged map[string]any
+			if err := json.Unmarshal([]byte(doc), &unchanged); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(original, unchanged) {
+				t.Errorf("ApplyJSONPatch() modified the document to %v", original)
+			}
+		})
+	}
+}
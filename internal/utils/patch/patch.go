@@ -0,0 +1,150 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+)
+
+// Media types accepted by the PATCH endpoints
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// Decode reads the patch document of the request into dst, a pointer to a
+// patch input schema whose fields are schemas.Optional.
+//
+// JSON Patch (RFC 6902) documents, sent as application/json-patch+json, are
+// applied to the current state of the resource and converted into the
+// equivalent merge patch. Any other body is decoded directly as a JSON Merge
+// Patch (RFC 7396), whether sent as application/merge-patch+json, plain
+// application/json or, as clients written before the patch media types did,
+// with another or no Content-Type, such as the form type sent by curl -d.
+// Fields unknown to dst are rejected in both cases.
+func Decode(r *http.Request, current any, dst any) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case JSONPatchContentType:
+		var ops []Operation
+		if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+			return err
+		}
+
+		doc, err := Document(current, dst)
+		if err != nil {
+			return err
+		}
+
+		patched, err := ApplyJSONPatch(doc, ops)
+		if err != nil {
+			return err
+		}
+
+		merge, err := json.Marshal(mergeDiff(doc, patched))
+		if err != nil {
+			return err
+		}
+		return decodeStrict(bytes.NewReader(merge), dst)
+
+	default:
+		return decodeStrict(r.Body, dst)
+	}
+}
+
+// Updates collects the fields set on the patch input schema, keyed by their
+// Go name, so they can be written with gorm's Updates. Unlike updating with
+// a struct, zero values are written too.
+func Updates(input any) map[string]any {
+	updates := make(map[string]any)
+
+	v := reflect.Indirect(reflect.ValueOf(input))
+	for i := range v.NumField() {
+		field, ok := v.Field(i).Interface().(schemas.PatchField)
+		if !ok {
+			continue
+		}
+		if value, set := field.Patched(); set {
+			updates[v.Type().Field(i).Name] = value
+		}
+	}
+	return updates
+}
+
+// Document builds the JSON document that JSON Patch operations are applied
+// to. It holds the fields of the patch input schema, under their JSON names,
+// with the values of the fields of the same Go name on the current resource.
+func Document(current any, input any) (map[string]any, error) {
+	doc := make(map[string]any)
+
+	resource := reflect.Indirect(reflect.ValueOf(current))
+	inputType := reflect.Indirect(reflect.ValueOf(input)).Type()
+
+	for i := range inputType.NumField() {
+		field := inputType.Field(i)
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+
+		value := resource.FieldByName(field.Name)
+		if !value.IsValid() {
+			continue
+		}
+
+		// round trip through JSON so the values have the same types as
+		// the ones decoded from the operations
+		raw, err := json.Marshal(value.Interface())
+		if err != nil {
+			return nil, err
+		}
+		var decoded any
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			return nil, err
+		}
+		doc[name] = decoded
+	}
+	return doc, nil
+}
+
+// mergeDiff returns the merge patch turning the original document into the
+// patched one. Removed fields are set to null.
+func mergeDiff(original, patched map[string]any) map[string]any {
+	diff := make(map[string]any)
+	for key, value := range patched {
+		if old, ok := original[key]; !ok || !reflect.DeepEqual(old, value) {
+			diff[key] = value
+		}
+	}
+	for key := range original {
+		if _, ok := patched[key]; !ok {
+			diff[key] = nil
+		}
+	}
+	return diff
+}
+
+func decodeStrict(body io.Reader, dst any) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(dst)
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
@@ -0,0 +1,92 @@
+package patch
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LeandroDeJesus-S/confectionery/internal/schemas"
+)
+
+type testResource struct {
+	Name   string
+	Price  int64
+	Tags   []string
+	Active bool
+}
+
+type testPatchInput struct {
+	Name   schemas.Optional[string]   `json:"name"`
+	Price  schemas.Optional[int64]    `json:"price"`
+	Tags   schemas.Optional[[]string] `json:"tags"`
+	Active schemas.Optional[bool]     `json:"active"`
+}
+
+func TestDecode(t *testing.T) {
+	current := testResource{Name: "Bolo", Price: 4500, Tags: []string{"chocolate"}, Active: true}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		// want is the decoded input, nil when decoding fails
+		want map[string]any
+	}{
+		{"merge patch", MergePatchContentType, `{"name":"Torta","tags":null}`,
+			map[string]any{"Name": "Torta", "Tags": []string(nil)}},
+		{"plain JSON", "application/json; charset=utf-8", `{"price":0}`, map[string]any{"Price": int64(0)}},
+		{"form type sent by curl", "application/x-www-form-urlencoded", `{"active":false}`,
+			map[string]any{"Active": false}},
+		{"no content type", "", `{}`, map[string]any{}},
+		{"merge patch unknown field", MergePatchContentType, `{"weight":1}`, nil},
+		{"merge patch wrong type", MergePatchContentType, `{"price":"10"}`, nil},
+		{"JSON patch", JSONPatchContentType,
+			`[{"op":"test","path":"/price","value":4500},{"op":"add","path":"/tags/-","value":"festa"},{"op":"replace","path":"/name","value":"Torta"}]`,
+			map[string]any{"Name": "Torta", "Tags": []string{"chocolate", "festa"}}},
+		{"JSON patch without changes", JSONPatchContentType, `[{"op":"replace","path":"/price","value":4500}]`,
+			map[string]any{}},
+		{"JSON patch remove", JSONPatchContentType, `[{"op":"remove","path":"/tags"}]`,
+			map[string]any{"Tags": []string(nil)}},
+		{"JSON patch unknown field", JSONPatchContentType, `[{"op":"add","path":"/weight","value":1}]`, nil},
+		{"JSON patch failed test", JSONPatchContentType, `[{"op":"test","path":"/price","value":1}]`, nil},
+		{"JSON patch not a list", JSONPatchContentType, `{"name":"Torta"}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "/cakes/1", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var input testPatchInput
+			err := Decode(r, current, &input)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("Decode() = %+v, want an error", input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got := Updates(input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Updates() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocument(t *testing.T) {
+	current := testResource{Name: "Bolo", Price: 4500, Active: true}
+	want := map[string]any{"name": "Bolo", "price": float64(4500), "tags": nil, "active": true}
+
+	got, err := Document(&current, &testPatchInput{})
+	if err != nil {
+		t.Fatalf("Document() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Document() = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package spreadsheet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// utf8BOM marks the CSV files as UTF-8, which spreadsheet applications such
+// as Excel need to show accented text correctly
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// csvWriter writes the rows as comma separated values
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	if _, err := w.Write(utf8BOM); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: csv.NewWriter(w)}, nil
+}
+
+func (w *csvWriter) Write(row []any) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = formatValue(value)
+	}
+	return w.w.Write(record)
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// readCSV reads the records of a CSV file. The files exported by
+// spreadsheets set to Portuguese are separated by semicolons, so the
+// delimiter is detected from the header.
+func readCSV(r io.Reader, maxRows int) ([][]string, error) {
+	buffered := bufio.NewReader(r)
+	if bom, err := buffered.Peek(len(utf8BOM)); err == nil && bytes.Equal(bom, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	// Peek returns the bytes available even when the file is shorter
+	start, _ := buffered.Peek(buffered.Size())
+	header, _, _ := bytes.Cut(start, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	var records [][]string
+	// end is the line where the last record ended
+	end := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// the reader skips the empty lines, which are kept as blank rows
+		// so the rows match the rows shown by the spreadsheets
+		start, _ := reader.FieldPos(0)
+		for range start - end - 1 {
+			if len(records) == maxRows {
+				return nil, ErrTooManyRows
+			}
+			records = append(records, nil)
+		}
+		if len(records) == maxRows {
+			return nil, ErrTooManyRows
+		}
+		records = append(records, record)
+
+		last := len(record) - 1
+		end, _ = reader.FieldPos(last)
+		end += strings.Count(record[last], "\n")
+	}
+}
@@ -0,0 +1,427 @@
+// Package spreadsheet reads and writes the CSV and XLSX files exchanged by
+// the import and export endpoints, converting their rows from and to the
+// API schemas by the JSON names of the fields.
+package spreadsheet
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Media types of the supported formats
+const (
+	CSV  = "text/csv"
+	XLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// ErrUnsupportedFormat is returned for media types other than CSV and XLSX.
+var ErrUnsupportedFormat = errors.New("unsupported spreadsheet format")
+
+// ErrTooManyRows is returned by ReadAll for sheets with more lines than
+// accepted.
+var ErrTooManyRows = errors.New("spreadsheet has too many rows")
+
+// ErrTooLarge is returned by ReadAll for workbooks with cells past the last
+// column read or parts too large once uncompressed, which would take too much
+// memory to read.
+var ErrTooLarge = errors.New("spreadsheet too large")
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Writer writes the rows of a spreadsheet as they are produced, so large
+// exports are streamed instead of buffered.
+type Writer interface {
+	// Write writes a row of values, such as the ones returned by Values.
+	Write(row []any) error
+	// Flush writes the buffered rows to the underlying writer.
+	Flush() error
+	// Close flushes the rows written and finishes the file.
+	Close() error
+}
+
+// NewWriter returns a Writer of the given format writing to w.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w)
+	case XLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// ReadAll reads the rows of the first sheet of a file of the given format,
+// one for each line of the sheet, including the blank ones. Sheets with more
+// than maxRows lines return ErrTooManyRows.
+func ReadAll(format string, r io.Reader, maxRows int) ([][]string, error) {
+	switch format {
+	case CSV:
+		return readCSV(r, maxRows)
+	case XLSX:
+		return readXLSX(r, maxRows)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// Blank reports whether the row has no value.
+func Blank(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatOf returns the format of a file from its media type or, when the
+// media type is generic, from the extension of its name.
+func FormatOf(mediaType, filename string) (string, error) {
+	switch mediaType {
+	case CSV, "application/csv", "application/vnd.ms-excel":
+		return CSV, nil
+	case XLSX:
+		return XLSX, nil
+	case "", "application/octet-stream":
+		break
+	default:
+		return "", ErrUnsupportedFormat
+	}
+
+	switch {
+	case strings.HasSuffix(strings.ToLower(filename), ".csv"):
+		return CSV, nil
+	case strings.HasSuffix(strings.ToLower(filename), ".xlsx"):
+		return XLSX, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// Header returns the columns of the spreadsheets holding values of the given
+// struct: the JSON names of its fields, including the ones of embedded
+// structs. Fields holding other resources are left out, and the lists and
+// documents, such as the tags or the nutrition facts of a cake, are stored
+// as JSON text.
+func Header(v any) []string {
+	var header []string
+	for _, field := range columns(reflect.TypeOf(v)) {
+		header = append(header, field.name)
+	}
+	return header
+}
+
+// Values returns the values of the columns listed by Header for the given
+// struct, as described on Cell.
+func Values(v any) []any {
+	value := reflect.Indirect(reflect.ValueOf(v))
+
+	var row []any
+	for _, field := range columns(value.Type()) {
+		fieldValue, err := value.FieldByIndexErr(field.index)
+		if err != nil {
+			row = append(row, nil)
+			continue
+		}
+		row = append(row, Cell(fieldValue.Interface()))
+	}
+	return row
+}
+
+// Cell returns the value written to the cell of a field holding v: the value
+// pointed to by pointers, or nil for nil pointers, lists and documents, and
+// the JSON text of the lists and documents.
+func Cell(v any) any {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil
+	}
+	if isValue(value.Type()) {
+		return value.Interface()
+	}
+
+	if (value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.IsNil() {
+		return nil
+	}
+	encoded, err := json.Marshal(value.Interface())
+	if err != nil {
+		return nil
+	}
+	return string(encoded)
+}
+
+// DecodeError lists the columns of a row whose values could not be converted
+// to the type of their field.
+type DecodeError struct {
+	Columns []string
+}
+
+func (e *DecodeError) Error() string {
+	return "invalid values on columns " + strings.Join(e.Columns, ", ")
+}
+
+// Decode sets the fields of dst, a pointer to a struct, from the values of
+// the row, matching the header to the JSON names of the fields ignoring
+// case. Columns without a matching field are ignored and empty values leave
+// the fields with their zero value. Values that cannot be converted are
+// reported with a *DecodeError.
+func Decode(header, row []string, dst any) error {
+	value := reflect.ValueOf(dst).Elem()
+
+	fields := make(map[string]column)
+	for _, field := range columns(value.Type()) {
+		fields[strings.ToLower(field.name)] = field
+	}
+
+	var invalid []string
+	for i, name := range header {
+		field, ok := fields[strings.ToLower(strings.TrimSpace(name))]
+		if !ok || i >= len(row) {
+			continue
+		}
+
+		cell := strings.TrimSpace(row[i])
+		if cell == "" {
+			continue
+		}
+		set := setValue
+		if field.json {
+			set = setJSON
+		}
+		if !set(value.FieldByIndex(field.index), cell) {
+			invalid = append(invalid, field.name)
+		}
+	}
+
+	if len(invalid) > 0 {
+		return &DecodeError{Columns: invalid}
+	}
+	return nil
+}
+
+// column is a field of a struct stored on a spreadsheet column, as JSON text
+// for the lists and documents
+type column struct {
+	name  string
+	index []int
+	json  bool
+}
+
+// columns lists the fields of the struct type stored on spreadsheet columns.
+func columns(t reflect.Type) []column {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var result []column
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			for _, embedded := range columns(fieldType) {
+				embedded.index = append([]int{i}, embedded.index...)
+				result = append(result, embedded)
+			}
+			continue
+		}
+
+		if IsResource(fieldType) {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		result = append(result, column{name: name, index: []int{i}, json: !isValue(fieldType)})
+	}
+	return result
+}
+
+// IsResource reports whether the type, or the elements of the slice type,
+// are the output schemas of other resources, which are not stored on the
+// spreadsheets.
+func IsResource(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && strings.HasSuffix(t.Name(), "OutputSchema")
+}
+
+// isValue reports whether the values of the type are stored on a column as
+// they are: plain values and times, or pointers to them, but not the lists
+// and documents.
+func isValue(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return t == timeType
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.Interface:
+		return false
+	}
+	return true
+}
+
+// setValue converts the cell to the type of the field and sets it, reporting
+// whether the conversion succeeded.
+func setValue(field reflect.Value, cell string) bool {
+	if field.Kind() == reflect.Pointer {
+		target := reflect.New(field.Type().Elem())
+		if !setValue(target.Elem(), cell) {
+			return false
+		}
+		field.Set(target)
+		return true
+	}
+
+	if field.Type() == timeType {
+		parsed, err := time.Parse(time.RFC3339, cell)
+		if err != nil {
+			return false
+		}
+		field.Set(reflect.ValueOf(parsed))
+		return true
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(unescapeFormula(cell))
+
+	case reflect.Bool:
+		parsed, err := parseBool(cell)
+		if err != nil {
+			return false
+		}
+		field.SetBool(parsed)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(cell, 10, field.Type().Bits())
+		if err != nil {
+			return false
+		}
+		field.SetInt(parsed)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(cell, 10, field.Type().Bits())
+		if err != nil {
+			return false
+		}
+		field.SetUint(parsed)
+
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(cell, field.Type().Bits())
+		if err != nil {
+			return false
+		}
+		field.SetFloat(parsed)
+
+	default:
+		return false
+	}
+	return true
+}
+
+// setJSON decodes the JSON text of the cell into the field, holding a list
+// or a document, reporting whether the text is valid for its type.
+func setJSON(field reflect.Value, cell string) bool {
+	target := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(unescapeFormula(cell)), target.Interface()); err != nil {
+		return false
+	}
+	field.Set(target.Elem())
+	return true
+}
+
+// parseBool accepts the values of strconv.ParseBool and their Portuguese
+// counterparts "sim" and "não".
+func parseBool(cell string) (bool, error) {
+	switch strings.ToLower(cell) {
+	case "sim", "s":
+		return true, nil
+	case "não", "nao", "n":
+		return false, nil
+	default:
+		return strconv.ParseBool(cell)
+	}
+}
+
+// formatValue returns the text of a value written to a cell. Text that
+// spreadsheet applications would run as a formula is escaped.
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return escapeFormula(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(rv.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return strconv.FormatUint(rv.Uint(), 10)
+		case reflect.Float32, reflect.Float64:
+			return strconv.FormatFloat(rv.Float(), 'f', -1, 64)
+		case reflect.String:
+			return escapeFormula(rv.String())
+		}
+		return ""
+	}
+}
+
+// formulaPrefixes are the characters starting a formula on spreadsheet
+// applications
+const formulaPrefixes = "=+-@"
+
+// escapeFormula prefixes the text starting with a formula character with an
+// apostrophe, so the applications opening the exports show it as text
+// instead of running it.
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune(formulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// unescapeFormula removes the apostrophe added by escapeFormula, so the
+// exported files are imported back with their original text.
+func unescapeFormula(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(text[1])) {
+		return text[1:]
+	}
+	return text
+}
@@ -0,0 +1,414 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAllergy struct {
+	Allergen string `json:"allergen"`
+	Severe   bool   `json:"severe"`
+}
+
+// Stats is exported as the embedded structs of the schemas are
+type Stats struct {
+	OrderCount int64 `json:"orderCount"`
+}
+
+type testOrderOutputSchema struct {
+	ID uint `json:"id"`
+}
+
+// testRecord holds a field of each kind stored on the spreadsheets
+type testRecord struct {
+	ID        uint            `json:"id"`
+	Name      string          `json:"name"`
+	Notes     string          `json:"notes"`
+	Price     int64           `json:"price"`
+	Weight    float64         `json:"weight"`
+	Active    bool            `json:"active"`
+	Email     *string         `json:"email"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Tags      []string        `json:"tags"`
+	Allergies []testAllergy   `json:"allergies"`
+	Details   json.RawMessage `json:"details"`
+	Hidden    string          `json:"-"`
+	*Stats
+	Orders []testOrderOutputSchema `json:"orders,omitzero"`
+}
+
+func TestHeader(t *testing.T) {
+	want := []string{
+		"id", "name", "notes", "price", "weight", "active", "email", "createdAt", "tags", "allergies", "details",
+		"orderCount",
+	}
+	if got := Header(testRecord{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Header() = %v, want %v", got, want)
+	}
+}
+
+func TestValues(t *testing.T) {
+	createdAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	record := testRecord{
+		ID: 1, Name: "Ana", Price: -5, Active: true, CreatedAt: createdAt,
+		Tags:      []string{"vip"},
+		Allergies: []testAllergy{{Allergen: "milk", Severe: true}},
+		Stats:     &Stats{OrderCount: 2},
+	}
+	want := []any{
+		uint(1), "Ana", "", int64(-5), 0.0, true, nil, createdAt, `["vip"]`,
+		`[{"allergen":"milk","severe":true}]`, nil, int64(2),
+	}
+	if got := Values(record); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %#v, want %#v", got, want)
+	}
+
+	// the fields of nil embedded structs are empty
+	if got := Values(testRecord{}); got[len(got)-1] != nil {
+		t.Errorf("Values() orderCount = %#v, want nil", got[len(got)-1])
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	email := "ana@example.com"
+	records := []testRecord{
+		{
+			ID: 1, Name: "Ana Souza", Notes: "=HYPERLINK(\"http://example.com\")", Price: 12050, Weight: 1.5,
+			Active: true, Email: &email, CreatedAt: time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC),
+			Tags:      []string{"vip", "family"},
+			Allergies: []testAllergy{{Allergen: "milk", Severe: true}, {Allergen: "nuts"}},
+			Details:   json.RawMessage(`{"message":"-feliz aniversário","candles":3}`),
+		},
+		{
+			ID: 2, Name: "+55 Bia", Notes: "@home\n-second line", Price: -300,
+			CreatedAt: time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+			Tags:      []string{}, Allergies: []testAllergy{},
+		},
+	}
+
+	for _, format := range []string{CSV, XLSX} {
+		t.Run(format, func(t *testing.T) {
+			var file bytes.Buffer
+			writer, err := NewWriter(format, &file)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			var header []any
+			for _, name := range Header(testRecord{}) {
+				header = append(header, name)
+			}
+			if err := writer.Write(header); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			for _, record := range records {
+				if err := writer.Write(Values(record)); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			rows, err := ReadAll(format, &file, 10)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if len(rows) != len(records)+1 {
+				t.Fatalf("ReadAll() returned %d rows, want %d", len(rows), len(records)+1)
+			}
+			if got := rows[1][2]; !strings.HasPrefix(got, "'=") {
+				t.Errorf("formula cell = %q, want it escaped with an apostrophe", got)
+			}
+
+			for i, want := range records {
+				var got testRecord
+				if err := Decode(rows[0], rows[i+1], &got); err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Decode() = %+v, want %+v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		text, escaped string
+	}{
+		{"", ""},
+		{"Ana", "Ana"},
+		{"=1+1", "'=1+1"},
+		{"+5511912345678", "'+5511912345678"},
+		{"-10", "'-10"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"'quoted", "'quoted"},
+		{"a=b", "a=b"},
+	}
+
+	for _, tt := range tests {
+		if got := formatValue(tt.text); got != tt.escaped {
+			t.Errorf("formatValue(%q) = %q, want %q", tt.text, got, tt.escaped)
+		}
+		if got := unescapeFormula(tt.escaped); got != tt.text {
+			t.Errorf("unescapeFormula(%q) = %q, want %q", tt.escaped, got, tt.text)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		row     []string
+		want    testRecord
+		invalid []string
+	}{
+		{
+			name:   "header ignoring case and spaces",
+			header: []string{" NAME ", "Price", "unknown", "active"},
+			row:    []string{"Ana", " 10 ", "ignored", "sim"},
+			want:   testRecord{Name: "Ana", Price: 10, Active: true},
+		},
+		{
+			name:   "empty cells and short rows",
+			header: []string{"name", "price", "email"},
+			row:    []string{"", ""},
+			want:   testRecord{},
+		},
+		{
+			name:   "portuguese booleans",
+			header: []string{"active"},
+			row:    []string{"não"},
+			want:   testRecord{Active: false},
+		},
+		{
+			name:    "invalid values",
+			header:  []string{"id", "price", "active", "createdAt", "tags", "allergies"},
+			row:     []string{"-1", "1.5", "maybe", "2025-03-01", "vip", `{"allergen":"milk"}`},
+			invalid: []string{"id", "price", "active", "createdAt", "tags", "allergies"},
+		},
+		{
+			name:   "lists and documents",
+			header: []string{"tags", "details"},
+			row:    []string{`["a","b"]`, `'-1`},
+			want:   testRecord{Tags: []string{"a", "b"}, Details: json.RawMessage(`-1`)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testRecord
+			err := Decode(tt.header, tt.row, &got)
+
+			var decodeErr *DecodeError
+			switch {
+			case tt.invalid == nil && err != nil:
+				t.Fatalf("Decode() error = %v", err)
+			case tt.invalid != nil && !errors.As(err, &decodeErr):
+				t.Fatalf("Decode() error = %v, want a DecodeError", err)
+			case tt.invalid != nil:
+				if !reflect.DeepEqual(decodeErr.Columns, tt.invalid) {
+					t.Errorf("DecodeError.Columns = %v, want %v", decodeErr.Columns, tt.invalid)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		maxRows int
+		want    [][]string
+		err     error
+	}{
+		{
+			name: "commas", file: "name,price\nAna,10\n", maxRows: 10,
+			want: [][]string{{"name", "price"}, {"Ana", "10"}},
+		},
+		{
+			name: "semicolons with byte order mark", file: "\ufeffname;notes\nAna;um, dois\n", maxRows: 10,
+			want: [][]string{{"name", "notes"}, {"Ana", "um, dois"}},
+		},
+		{
+			name: "blank lines keep their line", file: "name\n\nAna\n\n\nBia\n", maxRows: 10,
+			want: [][]string{{"name"}, nil, {"Ana"}, nil, nil, {"Bia"}},
+		},
+		{
+			name: "quoted line breaks", file: "name,notes\nAna,\"one\ntwo\"\nBia,three\n", maxRows: 10,
+			want: [][]string{{"name", "notes"}, {"Ana", "one\ntwo"}, {"Bia", "three"}},
+		},
+		{
+			name: "blank lines after line breaks", file: "name,notes\r\nAna,\"one\r\ntwo\"\r\n\r\nBia,three\r\n", maxRows: 10,
+			want: [][]string{{"name", "notes"}, {"Ana", "one\ntwo"}, nil, {"Bia", "three"}},
+		},
+		{
+			name: "rows up to the limit", file: "name\nAna\nBia\n", maxRows: 3,
+			want: [][]string{{"name"}, {"Ana"}, {"Bia"}},
+		},
+		{name: "too many rows", file: "name\nAna\nBia\n", maxRows: 2, err: ErrTooManyRows},
+		{name: "too many lines", file: "name\n\n\n\nAna\n", maxRows: 3, err: ErrTooManyRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadAll(CSV, strings.NewReader(tt.file), tt.maxRows)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ReadAll() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadAll() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// workbook returns an XLSX file holding a sheet with the given sheet data
+// and, when given, the shared strings.
+func workbook(t *testing.T, sheetData, sharedStrings string) []byte {
+	t.Helper()
+
+	parts := map[string]string{
+		"xl/workbook.xml":            xlsxWorkbook,
+		"xl/_rels/workbook.xml.rels": xlsxWorkbookRels,
+		"xl/worksheets/sheet1.xml":   xlsxSheetStart + sheetData + xlsxSheetEnd,
+	}
+	if sharedStrings != "" {
+		parts["xl/sharedStrings.xml"] = `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			sharedStrings + `</sst>`
+	}
+
+	var file bytes.Buffer
+	archive := zip.NewWriter(&file)
+	for name, content := range parts {
+		part, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return file.Bytes()
+}
+
+func TestReadXLSX(t *testing.T) {
+	tests := []struct {
+		name      string
+		sheetData string
+		shared    string
+		maxRows   int
+		want      [][]string
+		err       error
+	}{
+		{
+			name: "shared strings and rich text",
+			sheetData: `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>` +
+				`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>10.5</v></c></row>`,
+			shared:  `<si><t>name</t></si><si><t>price</t></si><si><r><t>Ana </t></r><r><t>Souza</t></r></si>`,
+			maxRows: 10,
+			want:    [][]string{{"name", "price"}, {"Ana Souza", "10.5"}},
+		},
+		{
+			name: "inline strings and booleans",
+			sheetData: `<row r="1"><c r="A1" t="inlineStr"><is><t>active</t></is></c></row>` +
+				`<row r="2"><c r="A2" t="b"><v>1</v></c></row>`,
+			maxRows: 10,
+			want:    [][]string{{"active"}, {"true"}},
+		},
+		{
+			name: "sparse rows and cells",
+			sheetData: `<row r="1"><c r="A1" t="inlineStr"><is><t>a</t></is></c><c r="C1"><v>3</v></c></row>` +
+				`<row r="4"><c r="B4"><v>2</v></c></row>`,
+			maxRows: 10,
+			want:    [][]string{{"a", "", "3"}, nil, nil, {"", "2"}},
+		},
+		{
+			name:      "cells without references",
+			sheetData: `<row><c><v>1</v></c><c><v>2</v></c></row><row><c><v>3</v></c></row>`,
+			maxRows:   10,
+			want:      [][]string{{"1", "2"}, {"3"}},
+		},
+		{
+			name:      "rows past the limit",
+			sheetData: `<row r="1"><c r="A1"><v>1</v></c></row><row r="5000000"><c r="A5000000"><v>1</v></c></row>`,
+			maxRows:   10,
+			err:       ErrTooManyRows,
+		},
+		{
+			name:      "columns past the limit",
+			sheetData: `<row r="1"><c r="XFD1"><v>1</v></c></row>`,
+			maxRows:   10,
+			err:       ErrTooLarge,
+		},
+		{
+			name:      "missing shared string",
+			sheetData: `<row r="1"><c r="A1" t="s"><v>3</v></c></row>`,
+			shared:    `<si><t>name</t></si>`,
+			maxRows:   10,
+			err:       errInvalidWorkbook,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := workbook(t, tt.sheetData, tt.shared)
+			got, err := ReadAll(XLSX, bytes.NewReader(file), tt.maxRows)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ReadAll() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadAll() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadXLSXLargePart(t *testing.T) {
+	// a single cell expanding past maxPartSize, which compresses to a few KB
+	cell := `<row r="1"><c r="A1" t="inlineStr"><is><t>` + strings.Repeat("a", maxPartSize) + `</t></is></c></row>`
+	file := workbook(t, cell, "")
+
+	if _, err := ReadAll(XLSX, bytes.NewReader(file), 10); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("ReadAll() error = %v, want %v", err, ErrTooLarge)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		mediaType, filename string
+		want                string
+		err                 error
+	}{
+		{CSV, "", CSV, nil},
+		{"application/vnd.ms-excel", "", CSV, nil},
+		{XLSX, "", XLSX, nil},
+		{"application/octet-stream", "Clientes.XLSX", XLSX, nil},
+		{"", "clientes.csv", CSV, nil},
+		{"", "clientes.ods", "", ErrUnsupportedFormat},
+		{"application/pdf", "clientes.csv", "", ErrUnsupportedFormat},
+	}
+
+	for _, tt := range tests {
+		got, err := FormatOf(tt.mediaType, tt.filename)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("FormatOf(%q, %q) = %q, %v, want %q, %v", tt.mediaType, tt.filename, got, err, tt.want, tt.err)
+		}
+	}
+}
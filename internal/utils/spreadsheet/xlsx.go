@@ -0,0 +1,388 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The parts of the workbooks written, holding a single sheet
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter writes the rows to the single sheet of a workbook. The other
+// parts are written upfront, so the rows are streamed into the sheet.
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet io.Writer
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{zip: archive, sheet: sheet}, nil
+}
+
+// Write writes the numbers and booleans as such and every other value,
+// including times, as inline strings.
+func (w *xlsxWriter) Write(row []any) error {
+	var b bytes.Buffer
+	b.WriteString("<row>")
+	for _, value := range row {
+		switch v := reflect.ValueOf(value); v.Kind() {
+		case reflect.Invalid:
+			b.WriteString("<c/>")
+
+		case reflect.Bool:
+			b.WriteString(`<c t="b"><v>`)
+			if v.Bool() {
+				b.WriteString("1")
+			} else {
+				b.WriteString("0")
+			}
+			b.WriteString("</v></c>")
+
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			fmt.Fprintf(&b, "<c><v>%s</v></c>", formatValue(value))
+
+		default:
+			b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(&b, []byte(formatValue(value))); err != nil {
+				return err
+			}
+			b.WriteString("</t></is></c>")
+		}
+	}
+	b.WriteString("</row>")
+
+	_, err := w.sheet.Write(b.Bytes())
+	return err
+}
+
+func (w *xlsxWriter) Flush() error {
+	return w.zip.Flush()
+}
+
+func (w *xlsxWriter) Close() error {
+	if _, err := io.WriteString(w.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+// Limits of the workbooks read. The cells of the sheets are padded up to the
+// rows and columns of their references, and the parts are compressed, so
+// both are bounded before taking memory for them.
+const (
+	// maxColumns is the number of columns read, well above the fields of
+	// the schemas imported
+	maxColumns = 256
+	// maxPartSize is the size in bytes of the largest part read, once
+	// uncompressed, well above the sheets of the largest imports
+	maxPartSize = 8 << 20
+)
+
+// errInvalidWorkbook is returned for XLSX files missing the parts describing
+// their sheets
+var errInvalidWorkbook = errors.New("invalid xlsx workbook")
+
+// The parts of the workbooks read
+type (
+	xlsxRelationships struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+
+	xlsxWorkbookSheets struct {
+		Sheets []struct {
+			RelationshipID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+
+	xlsxText struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	}
+
+	xlsxSharedStrings struct {
+		Items []xlsxText `xml:"si"`
+	}
+
+	xlsxCell struct {
+		Ref    string   `xml:"r,attr"`
+		Type   string   `xml:"t,attr"`
+		Value  string   `xml:"v"`
+		Inline xlsxText `xml:"is"`
+	}
+)
+
+// String returns the text, joining the runs of rich text.
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// readXLSX reads the cells of the first sheet of a workbook as text.
+func readXLSX(r io.Reader, maxRows int) ([][]string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var workbook xlsxWorkbookSheets
+	if err := decodePart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := decodePart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errInvalidWorkbook
+	}
+
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RelationshipID {
+			sheetPath = rel.Target
+		}
+	}
+	if strings.HasPrefix(sheetPath, "/") {
+		sheetPath = strings.TrimPrefix(sheetPath, "/")
+	} else {
+		sheetPath = path.Join("xl", sheetPath)
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodePart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := openPart(files, sheetPath)
+	if err != nil {
+		return nil, err
+	}
+	defer sheet.Close()
+
+	rows, err := readRows(xml.NewDecoder(sheet), shared, maxRows)
+	if sheet.tooLarge() {
+		return nil, ErrTooLarge
+	}
+	return rows, err
+}
+
+// readRows reads the rows of a sheet as they are decoded, one cell at a time,
+// so the sheet is never held whole in memory and the reading stops as soon as
+// it has more than maxRows lines.
+func readRows(decoder *xml.Decoder, shared xlsxSharedStrings, maxRows int) ([][]string, error) {
+	var rows [][]string
+	var row []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "row":
+				ref := 0
+				for _, attr := range token.Attr {
+					if attr.Name.Local == "r" {
+						if ref, err = strconv.Atoi(attr.Value); err != nil {
+							return nil, errInvalidWorkbook
+						}
+					}
+				}
+				if ref > maxRows || len(rows) >= maxRows {
+					return nil, ErrTooManyRows
+				}
+
+				// empty rows are left out of the sheet, but keep their line
+				for len(rows)+1 < ref {
+					rows = append(rows, nil)
+				}
+				row = nil
+
+			case "c":
+				var cell xlsxCell
+				if err := decoder.DecodeElement(&cell, &token); err != nil {
+					return nil, err
+				}
+
+				column := columnIndex(cell.Ref)
+				if column < 0 {
+					column = len(row)
+				}
+				if column >= maxColumns {
+					return nil, ErrTooLarge
+				}
+				for len(row) <= column {
+					row = append(row, "")
+				}
+
+				switch cell.Type {
+				case "s":
+					index, err := strconv.Atoi(cell.Value)
+					if err != nil || index < 0 || index >= len(shared.Items) {
+						return nil, errInvalidWorkbook
+					}
+					row[column] = shared.Items[index].String()
+				case "inlineStr":
+					row[column] = cell.Inline.String()
+				case "b":
+					row[column] = strconv.FormatBool(cell.Value == "1")
+				default:
+					row[column] = cell.Value
+				}
+			}
+
+		case xml.EndElement:
+			if token.Name.Local == "row" {
+				rows = append(rows, row)
+			}
+		}
+	}
+}
+
+// decodePart decodes the XML part of the given name of the workbook, reading
+// up to maxPartSize bytes of it.
+func decodePart(files map[string]*zip.File, name string, dst any) error {
+	part, err := openPart(files, name)
+	if err != nil {
+		return err
+	}
+	defer part.Close()
+
+	err = xml.NewDecoder(part).Decode(dst)
+	if part.tooLarge() {
+		return ErrTooLarge
+	}
+	return err
+}
+
+// xlsxPart reads a part of a workbook up to maxPartSize bytes.
+type xlsxPart struct {
+	io.LimitedReader
+	file io.Closer
+}
+
+// openPart opens the part of the given name of the workbook.
+func openPart(files map[string]*zip.File, name string) (*xlsxPart, error) {
+	file, ok := files[name]
+	if !ok {
+		return nil, errInvalidWorkbook
+	}
+	content, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxPart{LimitedReader: io.LimitedReader{R: content, N: maxPartSize + 1}, file: content}, nil
+}
+
+func (p *xlsxPart) Close() error {
+	return p.file.Close()
+}
+
+// tooLarge reports whether the part goes past maxPartSize.
+func (p *xlsxPart) tooLarge() bool {
+	return p.N == 0
+}
+
+// columnIndex returns the zero based column of a cell reference such as "B3",
+// or -1 when the reference is missing. Columns past maxColumns are returned
+// as maxColumns.
+func columnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = min(index*26+int(r-'A'+1), maxColumns+1)
+	}
+	return index - 1
+}